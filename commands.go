@@ -0,0 +1,521 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/database"
+	"MysqlToMongo/internal/introspect"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/migration"
+	"MysqlToMongo/internal/preview"
+	"MysqlToMongo/internal/search"
+	"MysqlToMongo/internal/server"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// signalContext retorna um contexto cancelado ao receber SIGINT ou SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// runMigrate apaga a collection de destino e migra a tabela, ou apenas converte os registros com --dry-run
+func runMigrate(g *globalOptions, args []string) {
+	flags := newFlagSet("migrate", g)
+	dryRun := flags.Bool("dry-run", false, "lê e converte os registros sem gravar no MongoDB")
+	parseFlags(flags, args)
+
+	// Inicia o timer
+	startTime := time.Now()
+
+	// Cancela a migração ao receber SIGINT ou SIGTERM
+	ctx, stop := signalContext()
+	defer stop()
+
+	// Carrega configuração
+	config := g.loadConfig()
+	if err := migration.CheckConfig(config); err != nil {
+		logging.Fatalf("Erro na configuração: %v", err)
+	}
+
+	// Conecta ao MySQL
+	mysqlDB, err := database.ConnectMySQL(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MySQL: %v", err)
+	}
+	defer mysqlDB.Close()
+	if err := migration.CheckSource(ctx, config, mysqlDB); err != nil {
+		mysqlDB.Close()
+		logging.Fatalf("Erro no mapeamento: %v", err)
+	}
+
+	// No dry-run o MongoDB não é acessado
+	if *dryRun {
+		logging.Info("Iniciando dry-run...")
+		if err := migration.DryRun(ctx, config, mysqlDB); err != nil {
+			if errors.Is(err, migration.ErrInterrupted) {
+				mysqlDB.Close()
+				os.Exit(exitInterrupted)
+			}
+			logging.Fatalf("Erro durante o dry-run: %v", err)
+		}
+		logging.Infof("Dry-run concluído em %v", time.Since(startTime).Round(time.Second))
+		return
+	}
+
+	// Conecta ao MongoDB
+	mongoClient, err := database.ConnectMongoDB(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	// Inicia migração
+	logging.Info("Iniciando migração...")
+	if err := migration.MigrateData(ctx, config, mysqlDB, mongoClient); err != nil {
+		if errors.Is(err, migration.ErrInterrupted) || ctx.Err() != nil {
+			logging.Infof("Migração interrompida após %v: %v", time.Since(startTime).Round(time.Second), err)
+			mongoClient.Disconnect(context.Background())
+			mysqlDB.Close()
+			os.Exit(exitInterrupted)
+		}
+		logging.Fatalf("Erro durante a migração: %v", err)
+	}
+
+	// Calcula e mostra o tempo total
+	duration := time.Since(startTime)
+	logging.Infof("Migração concluída com sucesso em %v!", duration)
+}
+
+// runValidateConfig carrega a configuração com os valores sobrescritos e valida. Com --connect verifica também
+// as colunas da tabela de origem. Encerra com código 1 se houver problemas
+func runValidateConfig(g *globalOptions, args []string) {
+	flags := newFlagSet("validate-config", g)
+	connect := flags.Bool("connect", false, "conecta ao MySQL e verifica se a tabela tem as colunas do mapeamento")
+	parseFlags(flags, args)
+
+	cfg := g.loadConfig()
+	if err := migration.CheckConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuração inválida: %v\n", err)
+		os.Exit(1)
+	}
+	if *connect {
+		ctx, stop := signalContext()
+		defer stop()
+		mysqlDB, err := database.ConnectMySQL(cfg)
+		if err != nil {
+			logging.Fatalf("Erro ao conectar ao MySQL: %v", err)
+		}
+		defer mysqlDB.Close()
+		if err := migration.CheckSource(ctx, cfg, mysqlDB); err != nil {
+			fmt.Fprintf(os.Stderr, "Mapeamento inválido para a tabela %s: %v\n", cfg.MySQL.Table, err)
+			mysqlDB.Close()
+			os.Exit(1)
+		}
+	}
+
+	job := cfg.General.Job
+	if job == "" {
+		job = "-"
+	}
+	fmt.Printf("Configuração válida (%s, %s)\n", g.configPath, g.mappingPath)
+	fmt.Printf("  Origem:  %s:%d/%s tabela %s\n", cfg.MySQL.Host, cfg.MySQL.Port, cfg.MySQL.Database, cfg.MySQL.Table)
+	fmt.Printf("  Destino: %s %s.%s\n", config.RedactURI(cfg.MongoDB.URI), cfg.MongoDB.Database, cfg.MongoDB.Collection)
+	fmt.Printf("  Job: %s\n", job)
+	// Apenas as chaves, para não expor senhas passadas por variáveis de ambiente ou com --set
+	for _, key := range config.EnvKeys() {
+		if _, ok := os.LookupEnv(config.EnvName(key)); ok {
+			fmt.Printf("  Sobrescrito por variável de ambiente: %s (%s)\n", key, config.EnvName(key))
+		}
+	}
+	for _, override := range g.overrides() {
+		key, _, _ := strings.Cut(override, "=")
+		fmt.Printf("  Sobrescrito na linha de comando: %s\n", key)
+	}
+}
+
+// runPreview mostra como os registros selecionados são convertidos, sem gravar no MongoDB
+func runPreview(g *globalOptions, args []string) {
+	flags := newFlagSet("preview", g)
+	id := flags.String("id", "", "valor da chave primária do registro")
+	where := flags.String("where", "", "condição SQL para selecionar os registros")
+	limit := flags.Int("limit", 10, "número máximo de registros")
+	parseFlags(flags, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	config := g.loadConfig()
+
+	mysqlDB, err := database.ConnectMySQL(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MySQL: %v", err)
+	}
+	defer mysqlDB.Close()
+
+	opts := preview.Options{ID: *id, Where: *where, Limit: *limit}
+	if err := preview.Run(ctx, os.Stdout, config, mysqlDB, opts); err != nil {
+		mysqlDB.Close()
+		logging.Fatalf("Erro no preview: %v", err)
+	}
+}
+
+// runVerify compara a origem com o MongoDB e grava o relatório de reconciliação.
+// Encerra com código 1 se houver diferenças
+func runVerify(g *globalOptions, args []string) {
+	flags := newFlagSet("verify", g)
+	sample := flags.Int("sample", 1000, "registros aleatórios reconvertidos e comparados campo a campo")
+	checksums := flags.Bool("checksums", true, "compara checksums de todos os chunks")
+	report := flags.String("report", "", "caminho do relatório JSON (padrão tmp/logs/verify_<timestamp>.json)")
+	parseFlags(flags, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	config := g.loadConfig()
+	if err := migration.CheckConfig(config); err != nil {
+		logging.Fatalf("Erro na configuração: %v", err)
+	}
+
+	mysqlDB, err := database.ConnectMySQL(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MySQL: %v", err)
+	}
+	defer mysqlDB.Close()
+	if err := migration.CheckSource(ctx, config, mysqlDB); err != nil {
+		mysqlDB.Close()
+		logging.Fatalf("Erro no mapeamento: %v", err)
+	}
+
+	mongoClient, err := database.ConnectMongoDB(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	opts := migration.VerifyOptions{Sample: *sample, Checksums: *checksums, Report: *report}
+	result, err := migration.Verify(ctx, config, mysqlDB, mongoClient, opts)
+	if err != nil {
+		mongoClient.Disconnect(context.Background())
+		mysqlDB.Close()
+		logging.Fatalf("Erro na verificação: %v", err)
+	}
+
+	logging.Infof("Ausentes: %d - sobrando: %d - diferentes: %d", result.MissingCount, result.ExtraCount, result.MismatchedCount)
+	if result.DuplicatesCount > 0 {
+		logging.Infof("Documentos ausentes ou alterados pelo tratamento de CPFs duplicados: %d", result.DuplicatesCount)
+	}
+	if !result.OK {
+		mongoClient.Disconnect(context.Background())
+		mysqlDB.Close()
+		logging.Fatalf("Verificação encontrou diferenças")
+	}
+	logging.Info("Verificação concluída sem diferenças")
+}
+
+// runIndexes reconcilia os índices existentes no MongoDB com os declarados na configuração
+func runIndexes(g *globalOptions, args []string) {
+	flags := newFlagSet("indexes", g)
+	check := flags.Bool("check", false, "apenas mostra as diferenças, sem alterar nada")
+	dropExtras := flags.Bool("drop-extras", false, "remove índices existentes que não foram declarados")
+	recreate := flags.Bool("recreate", false, "recria índices declarados cujas opções mudaram")
+	parseFlags(flags, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	config := g.loadConfig()
+
+	mongoClient, err := database.ConnectMongoDB(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	opts := migration.IndexReconcileOptions{Check: *check, DropExtras: *dropExtras, RecreateOld: *recreate}
+	if err := migration.ReconcileIndexes(ctx, mongoClient.Database(config.MongoDB.Database), config, opts); err != nil {
+		mongoClient.Disconnect(context.Background())
+		logging.Fatalf("Erro na reconciliação dos índices: %v", err)
+	}
+}
+
+// runSearch busca documentos migrados pelos critérios informados, que são combinados com E
+func runSearch(g *globalOptions, args []string) {
+	flags := newFlagSet("search", g)
+	var criteria search.Criteria
+	flags.StringVar(&criteria.CPF, "cpf", "", "CPF, com ou sem pontuação")
+	flags.StringVar(&criteria.Phone, "telefone", "", "telefone, com ou sem pontuação")
+	flags.StringVar(&criteria.Email, "email", "", "email")
+	flags.StringVar(&criteria.Name, "nome", "", "início do nome, sem diferenciar acentos e maiúsculas")
+	flags.BoolVar(&criteria.Phonetic, "fonetico", false, "busca o nome pela chave fonética")
+	flags.StringVar(&criteria.CEP, "cep", "", "CEP, com ou sem hífen")
+	flags.StringVar(&criteria.City, "cidade", "", "cidade, sem diferenciar acentos e maiúsculas")
+	page := flags.Int("page", 1, "página dos resultados")
+	pageSize := flags.Int("page-size", search.DefaultPageSize, "resultados por página")
+	format := flags.String("format", search.FormatTable, "formato da saída: table, json ou csv")
+	parseFlags(flags, args)
+	if err := search.CheckFormat(*format); err != nil {
+		logging.Fatalf("Erro na busca: %v", err)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	config := g.loadConfig()
+
+	mongoClient, err := database.ConnectMongoDB(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	criteria.SearchKeys = config.Search
+	collection := mongoClient.Database(config.MongoDB.Database).Collection(config.MongoDB.Collection)
+	result, err := search.Find(ctx, collection, criteria, search.Options{Page: *page, PageSize: *pageSize})
+	if err == nil {
+		err = search.Write(os.Stdout, *format, result)
+	}
+	if err != nil {
+		mongoClient.Disconnect(context.Background())
+		logging.Fatalf("Erro na busca: %v", err)
+	}
+}
+
+// runCount mostra o total de registros do MySQL e de documentos da collection principal e das collections de roteamento
+func runCount(g *globalOptions, args []string) {
+	flags := newFlagSet("count", g)
+	source := flags.Bool("mysql", true, "conta os registros da tabela de origem")
+	target := flags.Bool("mongo", true, "conta os documentos das collections de destino")
+	parseFlags(flags, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	config := g.loadConfig()
+
+	mysqlDB, mongoClient := connectForCount(config, *source, *target)
+	if mysqlDB != nil {
+		defer mysqlDB.Close()
+	}
+	if mongoClient != nil {
+		defer mongoClient.Disconnect(context.Background())
+	}
+
+	report, err := migration.Count(ctx, config, mysqlDB, mongoClient)
+	if err != nil {
+		logging.Fatalf("Erro na contagem: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if report.SourceCount >= 0 {
+		fmt.Fprintf(tw, "MySQL %s.%s\t%d\n", config.MySQL.Database, config.MySQL.Table, report.SourceCount)
+	}
+	if len(report.TargetCounts) > 0 {
+		var total int64
+		for _, name := range report.Collections {
+			fmt.Fprintf(tw, "MongoDB %s.%s\t%d\n", config.MongoDB.Database, name, report.TargetCounts[name])
+			total += report.TargetCounts[name]
+		}
+		if len(report.Collections) > 1 {
+			fmt.Fprintf(tw, "MongoDB total\t%d\n", total)
+		}
+		if report.SourceCount >= 0 {
+			fmt.Fprintf(tw, "Diferença (MySQL - MongoDB)\t%d\n", report.SourceCount-total)
+		}
+	}
+	tw.Flush()
+}
+
+// connectForCount conecta apenas aos bancos que serão contados
+func connectForCount(cfg *config.Config, source, target bool) (mysqlDB *sql.DB, mongoClient *mongo.Client) {
+	if !source && !target {
+		logging.Fatalf("Informe --mysql, --mongo ou ambos")
+	}
+	var err error
+	if source {
+		mysqlDB, err = database.ConnectMySQL(cfg)
+		if err != nil {
+			logging.Fatalf("Erro ao conectar ao MySQL: %v", err)
+		}
+	}
+	if target {
+		mongoClient, err = database.ConnectMongoDB(cfg)
+		if err != nil {
+			logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+		}
+	}
+	return mysqlDB, mongoClient
+}
+
+// runServe expõe a API HTTP de consulta sobre a collection migrada até receber SIGINT ou SIGTERM
+func runServe(g *globalOptions, args []string) {
+	flags := newFlagSet("serve", g)
+	addr := flags.String("addr", "", "endereço de escuta (padrão server.addr ou :8080)")
+	parseFlags(flags, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	config := g.loadConfig()
+	if *addr == "" {
+		*addr = config.Server.Addr
+	}
+
+	mongoClient, err := database.ConnectMongoDB(config)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	collection := mongoClient.Database(config.MongoDB.Database).Collection(config.MongoDB.Collection)
+	srv, err := server.New(config.Server, config.Search, collection)
+	if err == nil {
+		err = srv.ListenAndServe(ctx, *addr)
+	}
+	if err != nil {
+		mongoClient.Disconnect(context.Background())
+		logging.Fatalf("Erro na API de consulta: %v", err)
+	}
+}
+
+// runConfig mostra a configuração efetiva em config show, com senhas e chaves ocultadas
+func runConfig(g *globalOptions, args []string) {
+	flags := newFlagSet("config", g)
+	format := flags.String("format", config.FormatJSON, "formato da saída: json ou yaml")
+
+	action := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	parseFlags(flags, args)
+	if action != "show" {
+		fmt.Fprintf(flags.Output(), "Ação desconhecida: %q (use config show)\n\n", action)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg := g.loadConfig()
+	output, err := config.Render(cfg, *format)
+	if err != nil {
+		logging.Fatalf("Erro ao formatar a configuração: %v", err)
+	}
+	os.Stdout.Write(output)
+}
+
+// runGenerateMapping lê as colunas da tabela de origem e uma amostra dos registros e grava um mapeamento
+// sugerido para revisão, sem sobrescrever arquivos existentes a menos que --force seja informado
+func runGenerateMapping(g *globalOptions, args []string) {
+	flags := newFlagSet("generate-mapping", g)
+	output := flags.String("output", "", "arquivo gerado (padrão tmp/mapping_<tabela>.json)")
+	sample := flags.Int("sample", introspect.DefaultSample, "registros lidos para sugerir os conversores")
+	force := flags.Bool("force", false, "sobrescreve o arquivo se ele já existir")
+	parseFlags(flags, args)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	cfg := g.loadConfigWithoutMapping()
+	if *output == "" {
+		*output = filepath.Join("tmp", fmt.Sprintf("mapping_%s.json", cfg.MySQL.Table))
+	}
+	if _, err := os.Stat(*output); err == nil && !*force {
+		logging.Fatalf("Erro: %s já existe; use --force para sobrescrever", *output)
+	}
+
+	mysqlDB, err := database.ConnectMySQL(cfg)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MySQL: %v", err)
+	}
+	defer mysqlDB.Close()
+
+	columns, err := introspect.Columns(ctx, mysqlDB, cfg.MySQL.Database, cfg.MySQL.Table)
+	if err == nil {
+		err = introspect.Analyze(ctx, mysqlDB, cfg.MySQL.Table, columns, *sample)
+	}
+	if err != nil {
+		mysqlDB.Close()
+		logging.Fatalf("Erro ao analisar a tabela %s: %v", cfg.MySQL.Table, err)
+	}
+
+	mapping, unmapped := introspect.Propose(columns)
+	data, err := json.MarshalIndent(mapping, "", "    ")
+	if err != nil {
+		logging.Fatalf("Erro ao gerar o mapeamento: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(*output), 0755); err != nil {
+		logging.Fatalf("Erro ao criar diretório: %v", err)
+	}
+	if err := os.WriteFile(*output, append(data, '\n'), 0644); err != nil {
+		logging.Fatalf("Erro ao gravar %s: %v", *output, err)
+	}
+
+	introspect.WriteReport(os.Stdout, cfg.MySQL.Table, columns, unmapped)
+	fmt.Printf("\nMapeamento gravado em %s. Revise antes de usar com --mapping %s\n", *output, *output)
+}
+
+// runSchema exporta o validador $jsonSchema gerado do mapeamento em schema export, ou o aplica na
+// collection principal em schema apply
+func runSchema(g *globalOptions, args []string) {
+	flags := newFlagSet("schema", g)
+	output := flags.String("output", "", "arquivo gerado por schema export (padrão: saída padrão)")
+
+	action := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	parseFlags(flags, args)
+	if action != "export" && action != "apply" {
+		fmt.Fprintf(flags.Output(), "Ação desconhecida: %q (use schema export ou schema apply)\n\n", action)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg := g.loadConfig()
+	if err := migration.CheckConfig(cfg); err != nil {
+		logging.Fatalf("Erro na configuração: %v", err)
+	}
+
+	if action == "export" {
+		data, err := bson.MarshalExtJSONIndent(migration.Validator(cfg), false, false, "", "    ")
+		if err != nil {
+			logging.Fatalf("Erro ao gerar o validador: %v", err)
+		}
+		data = append(data, '\n')
+		if *output == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			logging.Fatalf("Erro ao gravar %s: %v", *output, err)
+		}
+		logging.Infof("Validador gravado em %s", *output)
+		return
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	mongoClient, err := database.ConnectMongoDB(cfg)
+	if err != nil {
+		logging.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	if err := migration.ApplyValidator(ctx, mongoClient.Database(cfg.MongoDB.Database), cfg); err != nil {
+		mongoClient.Disconnect(context.Background())
+		logging.Fatalf("Erro: %v", err)
+	}
+}
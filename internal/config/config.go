@@ -2,26 +2,38 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 // Config representa a configuração geral da aplicação
 type Config struct {
-	MySQL   MySQLConfig    `json:"mysql"`
-	MongoDB MongoDBConfig  `json:"mongodb"`
-	General GeneralConfig  `json:"general"`
-	Mapping *MappingConfig `json:"-"` // Não será carregado do config.json
+	MySQL      MySQLConfig      `json:"mysql"`
+	MongoDB    MongoDBConfig    `json:"mongodb"`
+	General    GeneralConfig    `json:"general"`
+	Rules      []RuleConfig     `json:"rules"`
+	Duplicates DuplicatesConfig `json:"duplicates"`
+	DeadLetter DeadLetterConfig `json:"dead_letter"`
+	Throttle   ThrottleConfig   `json:"throttle"`
+	Indexes    []IndexConfig    `json:"indexes"` // Vazio usa os índices padrão de cpf, nome, emails e telefones
+	Search     SearchConfig     `json:"search"`
+	Server     ServerConfig     `json:"server"`
+	Schema     SchemaConfig     `json:"schema"`
+	Metrics    MetricsConfig    `json:"metrics"`
+	Mapping    *MappingConfig   `json:"-"` // Não será carregado do config.json
 }
 
 // MySQLConfig representa a configuração de conexão com o MySQL
 type MySQLConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	Database string `json:"database"`
-	Table    string `json:"table"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	User       string `json:"user"`
+	Password   string `json:"password"`
+	Database   string `json:"database"`
+	Table      string `json:"table"`
+	PrimaryKey string `json:"primary_key"` // Coluna inteira e única usada para ler a tabela em ordem; vazio usa a chave primária
 }
 
 // MongoDBConfig representa a configuração de conexão com o MongoDB
@@ -33,9 +45,179 @@ type MongoDBConfig struct {
 
 // GeneralConfig representa configurações gerais da aplicação
 type GeneralConfig struct {
-	BatchSize       int `json:"batch_size"`
-	NumWorkers      int `json:"num_workers"`
-	ReportThreshold int `json:"report_threshold"`
+	BatchSize          AutoInt        `json:"batch_size"`  // Número ou "auto"
+	NumWorkers         AutoInt        `json:"num_workers"` // Número ou "auto"
+	ReportThreshold    int            `json:"report_threshold"`
+	ChunkSize          int            `json:"chunk_size"`          // Registros por chunk da fila de trabalho
+	ChunkAttempts      int            `json:"chunk_attempts"`      // Tentativas por chunk antes de marcá-lo como falho
+	Resume             bool           `json:"resume"`              // Retoma a partir do checkpoint em vez de recomeçar
+	ConsistentSnapshot bool           `json:"consistent_snapshot"` // Todos os leitores leem do mesmo ponto no tempo
+	Retry              RetryConfig    `json:"retry"`
+	Pipeline           PipelineConfig `json:"pipeline"`
+	Job                string         `json:"job"`        // Nome da migração; separa checkpoint e logs de migrações diferentes
+	LogLevel           string         `json:"log_level"`  // debug, info (padrão), warn ou error
+	LogFormat          string         `json:"log_format"` // text (padrão) ou json
+	LogDir             string         `json:"log_dir"`    // Diretório dos logs, dead-letter e relatórios; padrão tmp/logs
+}
+
+// PipelineConfig representa o número de goroutines de cada estágio e a capacidade das filas entre eles.
+// Zero ou ausente usa o valor padrão
+type PipelineConfig struct {
+	Readers        int `json:"readers"`         // Leitores do MySQL, padrão num_workers
+	Converters     int `json:"converters"`      // Conversores para BSON, padrão número de CPUs
+	Writers        int `json:"writers"`         // Gravadores no MongoDB, padrão num_workers
+	RowBuffer      int `json:"row_buffer"`      // Linhas aguardando conversão
+	DocumentBuffer int `json:"document_buffer"` // Documentos aguardando gravação
+}
+
+// AutoInt representa um inteiro que também aceita "auto" no JSON.
+// Zero ou ausente é tratado como "auto"
+type AutoInt struct {
+	Value int
+}
+
+// IsAuto indica se o valor deve ser calculado automaticamente
+func (a AutoInt) IsAuto() bool {
+	return a.Value <= 0
+}
+
+// UnmarshalJSON aceita um número ou a string "auto"
+func (a *AutoInt) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		if str == "auto" {
+			a.Value = 0
+			return nil
+		}
+		value, err := strconv.Atoi(str)
+		if err != nil {
+			return fmt.Errorf("valor inválido '%s': use um número ou \"auto\"", str)
+		}
+		a.Value = value
+		return nil
+	}
+	return json.Unmarshal(data, &a.Value)
+}
+
+// MarshalJSON grava "auto" ou o número configurado
+func (a AutoInt) MarshalJSON() ([]byte, error) {
+	if a.IsAuto() {
+		return json.Marshal("auto")
+	}
+	return json.Marshal(a.Value)
+}
+
+// RetryConfig representa a política de novas tentativas para falhas transitórias
+type RetryConfig struct {
+	MaxAttempts      int     `json:"max_attempts"`
+	InitialBackoffMs int     `json:"initial_backoff_ms"`
+	MaxBackoffMs     int     `json:"max_backoff_ms"`
+	Jitter           float64 `json:"jitter"` // Fração aleatória aplicada ao backoff (0 a 1)
+}
+
+// RuleConfig representa uma regra de filtro ou roteamento aplicada após a conversão
+type RuleConfig struct {
+	Name       string `json:"name"`
+	Field      string `json:"field"`
+	Operator   string `json:"operator"`
+	Value      string `json:"value"`
+	Action     string `json:"action"`
+	Collection string `json:"collection"` // Usado apenas pela ação "route"
+}
+
+// DuplicatesConfig representa a estratégia de tratamento de CPFs duplicados
+type DuplicatesConfig struct {
+	Strategy   string `json:"strategy"`   // keep_first, keep_newest, merge_contacts ou move_to_collection
+	Collection string `json:"collection"` // Collection que recebe os registros descartados
+}
+
+// DeadLetterConfig representa o destino dos registros que falharam na conversão ou inserção
+type DeadLetterConfig struct {
+	Collection     string   `json:"collection"`       // Collection do MongoDB (tem prioridade sobre o arquivo)
+	File           string   `json:"file"`             // Arquivo JSONL, padrão tmp/logs/dead_letter[_<job>].jsonl
+	MaxFailureRate *float64 `json:"max_failure_rate"` // Percentual de falhas tolerado antes de encerrar com erro; ausente não encerra
+}
+
+// ThrottleConfig representa os limites de velocidade da leitura para proteger o MySQL de origem.
+// Zero ou ausente significa sem limite
+type ThrottleConfig struct {
+	RowsPerSecond    int                    `json:"rows_per_second"`    // Linhas lidas por segundo
+	BatchesPerSecond int                    `json:"batches_per_second"` // Lotes gravados por segundo
+	Adaptive         AdaptiveThrottleConfig `json:"adaptive"`
+}
+
+// AdaptiveThrottleConfig representa o modo adaptativo, que reduz a velocidade da leitura
+// quando a carga, o atraso de replicação ou a latência do MySQL passam dos limites
+type AdaptiveThrottleConfig struct {
+	Enabled              bool        `json:"enabled"`
+	IntervalMs           int         `json:"interval_ms"`             // Intervalo entre as consultas, padrão 5000
+	MaxThreadsRunning    int         `json:"max_threads_running"`     // Limite de Threads_running do SHOW GLOBAL STATUS
+	MaxReplicaLagSeconds int         `json:"max_replica_lag_seconds"` // Limite de atraso da réplica
+	MaxQueryLatencyMs    int         `json:"max_query_latency_ms"`    // Limite de latência de uma consulta simples
+	MinLevel             float64     `json:"min_level"`               // Menor fração da velocidade permitida (0 a 1), padrão 0.05
+	Replica              MySQLConfig `json:"replica"`                 // Réplica consultada para o atraso; vazio consulta o próprio servidor de origem
+}
+
+// SearchConfig representa os campos auxiliares gravados para a busca por nome
+type SearchConfig struct {
+	NormalizedName bool `json:"normalized_name"` // Grava nome_busca: maiúsculas, sem acentos e espaços repetidos
+	PhoneticName   bool `json:"phonetic_name"`   // Grava nome_fonetico com a chave fonética do nome
+}
+
+// ServerConfig representa a API HTTP de consulta do modo serve
+type ServerConfig struct {
+	Addr              string         `json:"addr"`                // Endereço de escuta, padrão ":8080"
+	APIKeys           []APIKeyConfig `json:"api_keys"`            // Chaves aceitas; sem chaves o serve não inicia
+	RequestsPerMinute int            `json:"requests_per_minute"` // Limite padrão por chave, padrão 60
+	MaxPageSize       int            `json:"max_page_size"`       // Maior tamanho de página aceito, padrão 100
+	Fields            []string       `json:"fields"`              // Campos que podem ser retornados; vazio usa os campos da busca
+	QueryTimeoutMs    int            `json:"query_timeout_ms"`    // Tempo máximo de cada consulta ao MongoDB, padrão 10000
+}
+
+// APIKeyConfig representa uma chave de acesso à API
+type APIKeyConfig struct {
+	Name              string `json:"name"` // Identifica a chave nos logs, ex.: o time que a usa
+	Key               string `json:"key"`
+	RequestsPerMinute int    `json:"requests_per_minute"` // Zero usa o limite padrão do servidor
+}
+
+// SchemaConfig representa o validador $jsonSchema da collection principal, gerado a partir do mapeamento
+type SchemaConfig struct {
+	Enabled          bool   `json:"enabled"`           // Aplica o validador na migração, ao criar a collection
+	ValidationLevel  string `json:"validation_level"`  // strict (padrão), moderate ou off
+	ValidationAction string `json:"validation_action"` // error (padrão) ou warn
+}
+
+// MetricsConfig representa o endpoint /metrics no formato do Prometheus, exposto durante a migração
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled"`
+	Addr    string `json:"addr"` // Endereço de escuta, padrão ":2112"
+}
+
+// IndexConfig representa um índice declarado para uma collection
+type IndexConfig struct {
+	Name               string           `json:"name"`       // Padrão gerado como no MongoDB (ex.: cpf_1)
+	Collection         string           `json:"collection"` // Padrão é a collection principal
+	Keys               []IndexKeyConfig `json:"keys"`
+	Unique             bool             `json:"unique"`
+	Sparse             bool             `json:"sparse"`
+	PartialFilter      json.RawMessage  `json:"partial_filter"` // Filtro em Extended JSON
+	Collation          *CollationConfig `json:"collation"`
+	ExpireAfterSeconds *int32           `json:"expire_after_seconds"` // Índice TTL
+	DefaultLanguage    string           `json:"default_language"`     // Idioma dos índices de texto
+}
+
+// IndexKeyConfig representa um campo de um índice
+type IndexKeyConfig struct {
+	Field string `json:"field"`
+	Type  string `json:"type"` // asc (padrão), desc, text ou hashed
+}
+
+// CollationConfig representa a collation de um índice
+type CollationConfig struct {
+	Locale    string `json:"locale"`
+	Strength  int    `json:"strength"` // 1 ignora acentos e maiúsculas, 2 ignora apenas maiúsculas
+	CaseLevel bool   `json:"case_level"`
 }
 
 // MappingConfig representa o mapeamento das colunas
@@ -68,32 +250,83 @@ type MappingConfig struct {
 	} `json:"pessoas"`
 }
 
-// LoadConfig carrega a configuração do arquivo config.json
+// Caminhos padrão dos arquivos de configuração
+var (
+	DefaultConfigPath  = filepath.Join("config", "config.json")
+	DefaultMappingPath = filepath.Join("config", "mapping.json")
+)
+
+// LoadOptions representa de onde a configuração é carregada e os valores sobrescritos na linha de comando
+type LoadOptions struct {
+	ConfigPath  string   // Vazio usa config/config.json ou, se não existir, config/config.yaml
+	MappingPath string   // Vazio usa config/mapping.json; ignorado se a configuração tiver a seção mapping
+	Profile     string   // Perfil da seção profiles; vazio usa a variável MTM_PROFILE
+	Overrides   []string // "chave=valor", com a chave no formato mysql.host ou rules.0.action
+	SkipMapping bool     // Não carrega o mapeamento, para comandos que não dependem dele, como generate-mapping
+}
+
+// LoadConfig carrega a configuração dos arquivos config.json e mapping.json padrão
 func LoadConfig() (*Config, error) {
-	// Carrega config.json
-	configPath := filepath.Join("config", "config.json")
-	configFile, err := os.ReadFile(configPath)
+	return Load(LoadOptions{})
+}
+
+// Load carrega a configuração em JSON ou YAML com os fragmentos incluídos, aplica o perfil e os valores
+// sobrescritos e carrega o mapeamento
+func Load(opts LoadOptions) (*Config, error) {
+	configPath := opts.ConfigPath
+	if configPath == "" || configPath == DefaultConfigPath {
+		configPath = findDefaultConfig()
+	}
+	mappingPath := opts.MappingPath
+	if mappingPath == "" {
+		mappingPath = DefaultMappingPath
+	}
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv(EnvPrefix + "PROFILE")
+	}
+
+	// Carrega o arquivo de configuração e os includes
+	tree, err := readTree(configPath, nil)
 	if err != nil {
 		return nil, err
 	}
+	if err := applyProfile(tree, profile); err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", configPath, err)
+	}
+	mappingTree, hasMapping := tree[mappingKey]
+	delete(tree, mappingKey)
+
+	// Variáveis de ambiente MTM_* têm prioridade sobre o arquivo e --set sobre as variáveis
+	overrides := append(envOverrides(os.LookupEnv), opts.Overrides...)
+	configFile, err := applyOverrides(tree, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", configPath, err)
+	}
 
 	var config Config
 	if err := json.Unmarshal(configFile, &config); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("erro ao ler %s: %v", configPath, err)
 	}
 
-	// Carrega mapping.json
-	mappingPath := filepath.Join("config", "mapping.json")
-	mappingFile, err := os.ReadFile(mappingPath)
-	if err != nil {
-		return nil, err
+	if opts.SkipMapping {
+		return &config, nil
 	}
 
-	var mapping MappingConfig
-	if err := json.Unmarshal(mappingFile, &mapping); err != nil {
-		return nil, err
+	// Carrega o mapeamento da seção mapping ou do mapping.json
+	mappingSource := configPath
+	if !hasMapping {
+		fileTree, err := readTree(mappingPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		mappingTree, mappingSource = fileTree, mappingPath
+	}
+	mapping, err := decodeMapping(mappingTree)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler o mapeamento de %s: %v", mappingSource, err)
 	}
 
-	config.Mapping = &mapping
+	config.Mapping = mapping
 	return &config, nil
 }
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Prefixo das variáveis de ambiente que sobrescrevem a configuração
+const EnvPrefix = "MTM_"
+
+// Sufixo das chaves que leem o valor de um arquivo, como mysql.password_file
+const fileSuffix = "_file"
+
+// EnvName retorna a variável de ambiente da chave, ex.: MTM_MYSQL_PASSWORD para mysql.password
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// EnvKeys retorna as chaves da configuração que podem ser sobrescritas por variáveis de ambiente.
+// Listas são sobrescritas inteiras, em JSON; campos de texto também aceitam a chave <campo>_file
+func EnvKeys() []string {
+	return envKeys(reflect.TypeOf(Config{}), "")
+}
+
+func envKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		switch {
+		case fieldType.Kind() == reflect.Struct && !reflect.PointerTo(fieldType).Implements(unmarshalerType):
+			keys = append(keys, envKeys(fieldType, key+".")...)
+		case fieldType.Kind() == reflect.String:
+			keys = append(keys, key, key+fileSuffix)
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// envOverrides retorna os valores "chave=valor" das variáveis de ambiente definidas
+func envOverrides(lookup func(string) (string, bool)) []string {
+	var overrides []string
+	for _, key := range EnvKeys() {
+		if value, ok := lookup(EnvName(key)); ok {
+			overrides = append(overrides, key+"="+value)
+		}
+	}
+	return overrides
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Chaves do arquivo de configuração que não fazem parte de Config
+const (
+	includeKey  = "include"  // Fragmentos mesclados antes do próprio arquivo
+	profilesKey = "profiles" // Valores por ambiente, selecionados com --profile
+	mappingKey  = "mapping"  // Mapeamento das colunas, no lugar do mapping.json
+)
+
+// isYAML indica se o arquivo deve ser lido como YAML, pela extensão
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// findDefaultConfig retorna config/config.json ou, se ele não existir, config/config.yaml ou config/config.yml
+func findDefaultConfig() string {
+	if _, err := os.Stat(DefaultConfigPath); err == nil {
+		return DefaultConfigPath
+	}
+	base := strings.TrimSuffix(DefaultConfigPath, filepath.Ext(DefaultConfigPath))
+	for _, ext := range []string{".yaml", ".yml"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return DefaultConfigPath
+}
+
+// readTree lê um arquivo JSON ou YAML e mescla os fragmentos listados em include.
+// Os caminhos dos fragmentos são relativos ao arquivo que os inclui, e os valores do arquivo têm prioridade
+func readTree(path string, parents []string) (map[string]interface{}, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, parent := range parents {
+		if parent == absolute {
+			return nil, fmt.Errorf("include circular: %s", strings.Join(append(parents, absolute), " -> "))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := decodeTree(data, isYAML(path))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", path, err)
+	}
+
+	includes, err := includePaths(tree[includeKey])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	delete(tree, includeKey)
+
+	merged := map[string]interface{}{}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		fragment, err := readTree(include, append(parents, absolute))
+		if err != nil {
+			return nil, err
+		}
+		merge(merged, fragment)
+	}
+	merge(merged, tree)
+	return merged, nil
+}
+
+// decodeTree converte o conteúdo do arquivo em um objeto genérico, com os valores como no JSON
+func decodeTree(data []byte, yamlFile bool) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	if yamlFile {
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return tree, nil
+		}
+		// Regrava em JSON para que os tipos sejam os mesmos de um arquivo JSON
+		converted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// includePaths aceita include como um caminho ou uma lista de caminhos
+func includePaths(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		paths := make([]string, 0, len(v))
+		for _, item := range v {
+			path, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include deve ser um caminho ou uma lista de caminhos")
+			}
+			paths = append(paths, path)
+		}
+		return paths, nil
+	}
+	return nil, fmt.Errorf("include deve ser um caminho ou uma lista de caminhos")
+}
+
+// merge grava os valores de src em dst. Objetos são mesclados campo a campo; listas e demais valores são substituídos
+func merge(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcObject, srcIsObject := value.(map[string]interface{})
+		dstObject, dstIsObject := dst[key].(map[string]interface{})
+		if srcIsObject && dstIsObject {
+			merge(dstObject, srcObject)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// applyProfile mescla os valores do perfil sobre a configuração e remove a seção profiles
+func applyProfile(tree map[string]interface{}, profile string) error {
+	profiles, _ := tree[profilesKey].(map[string]interface{})
+	if _, ok := tree[profilesKey]; ok && profiles == nil {
+		return fmt.Errorf("profiles deve ser um objeto com um item por perfil")
+	}
+	delete(tree, profilesKey)
+	if profile == "" {
+		return nil
+	}
+
+	values, ok := profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("perfil %q não encontrado: a configuração não declara profiles", profile)
+		}
+		return fmt.Errorf("perfil %q não encontrado (disponíveis: %s)", profile, strings.Join(names, ", "))
+	}
+	object, ok := values.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("perfil %q deve ser um objeto", profile)
+	}
+	merge(tree, object)
+	return nil
+}
+
+// decodeMapping converte o mapeamento, rejeitando chaves desconhecidas, que deixariam o campo pretendido sem coluna
+func decodeMapping(tree interface{}) (*MappingConfig, error) {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	var mapping MappingConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&mapping); err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
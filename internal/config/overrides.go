@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tipo dos campos que aceitam qualquer JSON, como filtros em Extended JSON
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Tipos com leitura própria do JSON, como AutoInt, não têm subcampos
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// applyOverrides aplica os valores "chave=valor" sobre a configuração lida do arquivo e lê os arquivos das chaves <campo>_file.
+// A chave segue os nomes do JSON separados por ponto; posições de listas são números (rules.0.action)
+func applyOverrides(tree interface{}, overrides []string) ([]byte, error) {
+	for _, override := range overrides {
+		key, raw, ok := strings.Cut(override, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("valor sobrescrito inválido %q: use chave=valor", override)
+		}
+		path := strings.Split(key, ".")
+		fieldType, err := keyType(reflect.TypeOf(Config{}), path)
+		if err != nil {
+			return nil, fmt.Errorf("chave %q: %v", key, err)
+		}
+		tree, err = setPath(tree, path, overrideValue(fieldType, raw))
+		if err != nil {
+			return nil, fmt.Errorf("chave %q: %v", key, err)
+		}
+		dropAlternative(tree, path)
+	}
+
+	if err := readSecretFiles(tree, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+// keyType retorna o tipo do campo da configuração indicado pelo caminho, ou erro se ele não existir
+func keyType(t reflect.Type, path []string) (reflect.Type, error) {
+	for i, segment := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == rawMessageType {
+			// Campos em JSON livre aceitam qualquer subcampo
+			return t, nil
+		}
+		if reflect.PointerTo(t).Implements(unmarshalerType) {
+			return nil, fmt.Errorf("%q não tem subcampos", strings.Join(path[:i], "."))
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := jsonField(t, segment)
+			if !ok && i == len(path)-1 {
+				field, ok = secretFileField(t, segment)
+			}
+			if !ok {
+				return nil, fmt.Errorf("campo desconhecido %q", strings.Join(path[:i+1], "."))
+			}
+			t = field.Type
+		case reflect.Slice, reflect.Array:
+			if _, err := strconv.Atoi(segment); err != nil {
+				return nil, fmt.Errorf("%q é uma lista; use a posição do item (ex.: %s.0)", strings.Join(path[:i], "."), strings.Join(path[:i], "."))
+			}
+			t = t.Elem()
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%q não tem subcampos", strings.Join(path[:i], "."))
+		}
+	}
+	return t, nil
+}
+
+// jsonField procura o campo da struct pelo nome usado no JSON
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name && tag != "-" {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// secretFileField retorna o campo de texto lido do arquivo indicado pela chave <campo>_file
+func secretFileField(t reflect.Type, name string) (reflect.StructField, bool) {
+	base, ok := strings.CutSuffix(name, fileSuffix)
+	if !ok {
+		return reflect.StructField{}, false
+	}
+	field, ok := jsonField(t, base)
+	if !ok || field.Type.Kind() != reflect.String {
+		return reflect.StructField{}, false
+	}
+	return field, true
+}
+
+// overrideValue converte o valor informado na linha de comando conforme o tipo do campo.
+// Campos de texto recebem o valor como está; os demais aceitam JSON (números, true/false, listas)
+func overrideValue(t reflect.Type, raw string) interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String {
+		return raw
+	}
+	if json.Valid([]byte(raw)) {
+		return json.RawMessage(raw)
+	}
+	// Valores como "auto" ficam como texto; tipos incompatíveis falham ao carregar a configuração
+	return raw
+}
+
+// setPath grava o valor no caminho da árvore JSON, criando os objetos intermediários
+func setPath(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	segment := path[0]
+
+	switch n := node.(type) {
+	case nil:
+		if _, err := strconv.Atoi(segment); err == nil {
+			return setPath([]interface{}{}, path, value)
+		}
+		return setPath(map[string]interface{}{}, path, value)
+	case map[string]interface{}:
+		child, err := setPath(n[segment], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[segment] = child
+		return n, nil
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index > len(n) {
+			return nil, fmt.Errorf("posição %s fora da lista de %d itens", segment, len(n))
+		}
+		if index == len(n) {
+			n = append(n, nil)
+		}
+		child, err := setPath(n[index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	}
+	return nil, fmt.Errorf("%q não é um objeto na configuração", segment)
+}
+
+// dropAlternative remove a alternativa da chave sobrescrita, para que prevaleça o último valor informado:
+// mysql.password remove mysql.password_file do mesmo objeto e vice-versa
+func dropAlternative(tree interface{}, path []string) {
+	parent := tree
+	for _, segment := range path[:len(path)-1] {
+		switch n := parent.(type) {
+		case map[string]interface{}:
+			parent = n[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(n) {
+				return
+			}
+			parent = n[index]
+		default:
+			return
+		}
+	}
+	object, ok := parent.(map[string]interface{})
+	if !ok {
+		return
+	}
+	name := path[len(path)-1]
+	if base, ok := strings.CutSuffix(name, fileSuffix); ok {
+		delete(object, base)
+	} else {
+		delete(object, name+fileSuffix)
+	}
+}
+
+// readSecretFiles substitui as chaves <campo>_file pelo conteúdo do arquivo indicado, sem a quebra de linha final.
+// Permite usar secrets do Docker e do Kubernetes, como mysql.password_file=/run/secrets/mysql_password
+func readSecretFiles(node interface{}, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == rawMessageType || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if field, ok := jsonField(t, key); ok {
+				if err := readSecretFiles(n[key], field.Type, path+key+"."); err != nil {
+					return err
+				}
+				continue
+			}
+			field, ok := secretFileField(t, key)
+			if !ok {
+				continue
+			}
+			base, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if _, exists := n[base]; exists {
+				return fmt.Errorf("%s%s e %s%s informados; use apenas um", path, base, path, key)
+			}
+			file, ok := n[key].(string)
+			if !ok || file == "" {
+				return fmt.Errorf("%s%s deve ser o caminho de um arquivo", path, key)
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("%s%s: %v", path, key, err)
+			}
+			n[base] = strings.TrimRight(string(content), "\r\n")
+			delete(n, key)
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			return nil
+		}
+		for i, item := range n {
+			if err := readSecretFiles(item, t.Elem(), fmt.Sprintf("%s%d.", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Configuração lida do arquivo nos testes
+const baseConfig = `{
+	"mysql": {"host": "localhost", "port": 3306},
+	"general": {"batch_size": 1000, "num_workers": "auto"},
+	"rules": [{"name": "cpf", "field": "cpf", "operator": "invalid_cpf", "action": "skip"}]
+}`
+
+func TestApplyOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []string
+		want      string // Trechos esperados no JSON resultante, ou o erro
+		wantErr   bool
+	}{
+		{"texto", []string{"mysql.host=db.interno"}, `"host":"db.interno"`, false},
+		{"texto que parece número continua texto", []string{"mysql.user=123"}, `"user":"123"`, false},
+		{"número", []string{"mysql.port=3307"}, `"port":3307`, false},
+		{"booleano", []string{"general.resume=true"}, `"resume":true`, false},
+		{"número opcional", []string{"dead_letter.max_failure_rate=0.5"}, `"max_failure_rate":0.5`, false},
+		{"auto", []string{"general.batch_size=auto"}, `"batch_size":"auto"`, false},
+		{"objeto criado", []string{"mongodb.database=cadastro"}, `"mongodb":{"database":"cadastro"}`, false},
+		{"item de lista", []string{"rules.0.action=route", "rules.0.collection=invalidos"}, `"action":"route","collection":"invalidos"`, false},
+		{"item novo no fim da lista", []string{"rules.1.name=obitos"}, `{"name":"obitos"}`, false},
+		{"lista inteira em JSON", []string{`server.fields=["cpf","nome"]`}, `"fields":["cpf","nome"]`, false},
+		{"última ocorrência prevalece", []string{"mysql.host=a", "mysql.host=b"}, `"host":"b"`, false},
+		{"valor substitui o arquivo", []string{"mysql.password_file=/run/secrets/senha", "mysql.password=segredo"}, `"password":"segredo"`, false},
+		{"sem igual", []string{"mysql.host"}, "use chave=valor", true},
+		{"chave vazia", []string{"=valor"}, "use chave=valor", true},
+		{"campo desconhecido", []string{"mysql.hots=x"}, `campo desconhecido "mysql.hots"`, true},
+		{"lista sem posição", []string{"rules.action=skip"}, "é uma lista", true},
+		{"posição fora da lista", []string{"rules.5.action=skip"}, "fora da lista", true},
+		{"subcampo de valor simples", []string{"mysql.port.x=1"}, "não tem subcampos", true},
+		{"subcampo de auto", []string{"general.batch_size.value=1"}, "não tem subcampos", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tree map[string]interface{}
+			if err := json.Unmarshal([]byte(baseConfig), &tree); err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyOverrides(tree, tt.overrides)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("applyOverrides(%v) erro = %v, esperado %q", tt.overrides, err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyOverrides(%v) erro = %v", tt.overrides, err)
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Errorf("applyOverrides(%v) = %s, esperado conter %s", tt.overrides, got, tt.want)
+			}
+			if strings.Contains(string(got), "password_file") {
+				t.Errorf("applyOverrides(%v) manteve password_file: %s", tt.overrides, got)
+			}
+			var config Config
+			if err := json.Unmarshal(got, &config); err != nil {
+				t.Errorf("resultado não é uma configuração válida: %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyOverridesSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "senha")
+	if err := os.WriteFile(secret, []byte("s3gr3d0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		tree      string
+		overrides []string
+		want      map[string]interface{} // Seção mysql esperada
+		wantErr   string
+	}{
+		{
+			name: "arquivo do config.json",
+			tree: `{"mysql": {"password_file": "` + filepath.ToSlash(secret) + `"}}`,
+			want: map[string]interface{}{"password": "s3gr3d0"},
+		},
+		{
+			name:      "arquivo da linha de comando substitui o valor",
+			tree:      `{"mysql": {"password": "antiga"}}`,
+			overrides: []string{"mysql.password_file=" + secret},
+			want:      map[string]interface{}{"password": "s3gr3d0"},
+		},
+		{
+			name:    "valor e arquivo no mesmo objeto",
+			tree:    `{"mysql": {"password": "antiga", "password_file": "` + filepath.ToSlash(secret) + `"}}`,
+			wantErr: "use apenas um",
+		},
+		{
+			name:    "arquivo inexistente",
+			tree:    `{"mysql": {"password_file": "` + filepath.ToSlash(filepath.Join(dir, "nada")) + `"}}`,
+			wantErr: "mysql.password_file",
+		},
+		{
+			name:      "_file apenas em campos de texto",
+			tree:      `{}`,
+			overrides: []string{"mysql.port_file=" + secret},
+			wantErr:   "campo desconhecido",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tree map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.tree), &tree); err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyOverrides(tree, tt.overrides)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("erro = %v, esperado %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result map[string]map[string]interface{}
+			if err := json.Unmarshal(got, &result); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result["mysql"], tt.want) {
+				t.Errorf("mysql = %v, esperado %v", result["mysql"], tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"MTM_MYSQL_HOST":          "db",
+		"MTM_MYSQL_PASSWORD_FILE": "/run/secrets/senha",
+		"MTM_GENERAL_BATCH_SIZE":  "500",
+		"MTM_DESCONHECIDA":        "x",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	got := envOverrides(lookup)
+	want := []string{"mysql.host=db", "mysql.password_file=/run/secrets/senha", "general.batch_size=500"}
+	for _, override := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == override
+		}
+		if !found {
+			t.Errorf("envOverrides() = %v, esperado conter %q", got, override)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("envOverrides() = %v, esperados %d valores", got, len(want))
+	}
+}
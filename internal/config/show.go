@@ -0,0 +1,59 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formatos aceitos por Render
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Render formata a configuração efetiva, com o mapeamento e com senhas e chaves ocultadas
+func Render(cfg *Config, format string) ([]byte, error) {
+	effective := struct {
+		*Config
+		Mapping *MappingConfig `json:"mapping"`
+	}{cfg.Redacted(), cfg.Mapping}
+
+	data, err := json.MarshalIndent(effective, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatJSON:
+		return append(data, '\n'), nil
+	case FormatYAML:
+		// Lido como nó para manter a ordem dos campos da struct
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		clearStyle(&node)
+		var out bytes.Buffer
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+	return nil, fmt.Errorf("formato inválido: %q (use %s ou %s)", format, FormatJSON, FormatYAML)
+}
+
+// clearStyle remove o estilo herdado do JSON (chaves e aspas) para que o YAML use o estilo em blocos
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"MysqlToMongo/internal/logging"
+)
+
+// Nomes aceitos em general.job, que fazem parte de nomes de arquivos
+var jobNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Problems acumula os problemas encontrados na validação, para que sejam informados todos de uma vez
+type Problems []string
+
+// Add registra um problema
+func (p *Problems) Add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// Err retorna nil se não houver problemas ou um *ValidationError com todos eles
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+// ValidationError reúne os problemas da configuração
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%d problemas encontrados:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate verifica os campos obrigatórios e os valores do config.json, retornando todos os problemas encontrados.
+// O mapeamento é verificado por models.CheckMapping
+func Validate(cfg *Config) Problems {
+	var problems Problems
+
+	required := []struct{ key, value string }{
+		{"mysql.host", cfg.MySQL.Host},
+		{"mysql.database", cfg.MySQL.Database},
+		{"mysql.table", cfg.MySQL.Table},
+		{"mongodb.uri", cfg.MongoDB.URI},
+		{"mongodb.database", cfg.MongoDB.Database},
+		{"mongodb.collection", cfg.MongoDB.Collection},
+	}
+	for _, field := range required {
+		if strings.TrimSpace(field.value) == "" {
+			problems.Add("%s é obrigatório", field.key)
+		}
+	}
+	if cfg.MySQL.Port < 0 || cfg.MySQL.Port > 65535 {
+		problems.Add("mysql.port inválida: %d", cfg.MySQL.Port)
+	}
+
+	// Zero em batch_size e num_workers equivale a "auto"
+	for _, field := range []struct {
+		key   string
+		value AutoInt
+	}{
+		{"general.batch_size", cfg.General.BatchSize},
+		{"general.num_workers", cfg.General.NumWorkers},
+	} {
+		if field.value.Value < 0 {
+			problems.Add("%s deve ser positivo ou \"auto\": %d", field.key, field.value.Value)
+		}
+	}
+
+	nonNegative := []struct {
+		key   string
+		value int
+	}{
+		{"general.report_threshold", cfg.General.ReportThreshold},
+		{"general.chunk_size", cfg.General.ChunkSize},
+		{"general.chunk_attempts", cfg.General.ChunkAttempts},
+		{"general.retry.max_attempts", cfg.General.Retry.MaxAttempts},
+		{"general.retry.initial_backoff_ms", cfg.General.Retry.InitialBackoffMs},
+		{"general.retry.max_backoff_ms", cfg.General.Retry.MaxBackoffMs},
+		{"general.pipeline.readers", cfg.General.Pipeline.Readers},
+		{"general.pipeline.converters", cfg.General.Pipeline.Converters},
+		{"general.pipeline.writers", cfg.General.Pipeline.Writers},
+		{"general.pipeline.row_buffer", cfg.General.Pipeline.RowBuffer},
+		{"general.pipeline.document_buffer", cfg.General.Pipeline.DocumentBuffer},
+		{"throttle.rows_per_second", cfg.Throttle.RowsPerSecond},
+		{"throttle.batches_per_second", cfg.Throttle.BatchesPerSecond},
+		{"server.requests_per_minute", cfg.Server.RequestsPerMinute},
+		{"server.max_page_size", cfg.Server.MaxPageSize},
+		{"server.query_timeout_ms", cfg.Server.QueryTimeoutMs},
+	}
+	for _, field := range nonNegative {
+		if field.value < 0 {
+			problems.Add("%s não pode ser negativo: %d", field.key, field.value)
+		}
+	}
+
+	if jitter := cfg.General.Retry.Jitter; jitter < 0 || jitter > 1 {
+		problems.Add("general.retry.jitter deve estar entre 0 e 1: %g", jitter)
+	}
+	if rate := cfg.DeadLetter.MaxFailureRate; rate != nil && (*rate < 0 || *rate > 100) {
+		problems.Add("dead_letter.max_failure_rate deve ser um percentual entre 0 e 100: %g", *rate)
+	}
+
+	switch cfg.Schema.ValidationLevel {
+	case "", "strict", "moderate", "off":
+	default:
+		problems.Add("schema.validation_level inválido: %q (use strict, moderate ou off)", cfg.Schema.ValidationLevel)
+	}
+	switch cfg.Schema.ValidationAction {
+	case "", "error", "warn":
+	default:
+		problems.Add("schema.validation_action inválido: %q (use error ou warn)", cfg.Schema.ValidationAction)
+	}
+
+	if addr := cfg.Metrics.Addr; cfg.Metrics.Enabled && addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			problems.Add("metrics.addr inválido: %q (use host:porta ou :porta)", addr)
+		}
+	}
+
+	if cfg.General.Job != "" && !jobNamePattern.MatchString(cfg.General.Job) {
+		problems.Add("general.job inválido: %q (use letras, números, - e _)", cfg.General.Job)
+	}
+	if _, err := logging.ParseLevel(cfg.General.LogLevel); err != nil {
+		problems.Add("general.log_level: %v", err)
+	}
+	if _, err := logging.ParseFormat(cfg.General.LogFormat); err != nil {
+		problems.Add("general.log_format: %v", err)
+	}
+
+	if cfg.Mapping == nil {
+		problems.Add("mapeamento não carregado")
+	}
+	return problems
+}
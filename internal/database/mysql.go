@@ -10,15 +10,12 @@ import (
 )
 
 func ConnectMySQL(config *config.Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		config.MySQL.User,
-		config.MySQL.Password,
-		config.MySQL.Host,
-		config.MySQL.Port,
-		config.MySQL.Database,
-	)
+	return OpenMySQL(config.MySQL)
+}
 
-	db, err := sql.Open("mysql", dsn)
+// OpenMySQL abre e testa uma conexão com o servidor MySQL informado
+func OpenMySQL(cfg config.MySQLConfig) (*sql.DB, error) {
+	db, err := sql.Open("mysql", cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("erro ao conectar ao MySQL: %v", err)
 	}
@@ -0,0 +1,133 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Etapas em que um registro pode falhar
+const (
+	StageConversion = "conversion"
+	StageInsert     = "insert"
+)
+
+// Entry representa um registro que falhou na conversão ou na inserção
+type Entry struct {
+	WorkerID  int       `bson:"worker_id"`
+	Stage     string    `bson:"stage"`
+	Error     string    `bson:"error"`
+	SourceRow bson.D    `bson:"source_row"`
+	Document  any       `bson:"document,omitempty"`
+	Timestamp time.Time `bson:"timestamp"`
+}
+
+// Sink grava os registros com falha em uma collection do MongoDB ou em um arquivo JSONL
+type Sink struct {
+	collection *mongo.Collection
+	path       string
+	file       *os.File // Aberto na primeira gravação, para não criar arquivos vazios
+	mu         sync.Mutex
+	count      atomic.Int64
+}
+
+// NewCollectionSink cria um destino que grava na collection informada
+func NewCollectionSink(collection *mongo.Collection) *Sink {
+	return &Sink{collection: collection}
+}
+
+// NewFileSink cria um destino que grava uma linha Extended JSON por registro no arquivo informado.
+// O diretório é criado de imediato para que erros de permissão apareçam antes da carga; o arquivo, só na primeira falha
+func NewFileSink(path string) (*Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório do dead-letter: %v", err)
+	}
+	return &Sink{path: path}, nil
+}
+
+// Destination descreve onde os registros estão sendo gravados
+func (s *Sink) Destination() string {
+	if s.collection != nil {
+		return fmt.Sprintf("collection '%s'", s.collection.Name())
+	}
+	return fmt.Sprintf("arquivo '%s'", s.path)
+}
+
+// Count retorna quantos registros foram gravados
+func (s *Sink) Count() int64 {
+	return s.count.Load()
+}
+
+// Resume soma ao contador os registros gravados por uma execução anterior que está sendo retomada
+func (s *Sink) Resume(previous int64) {
+	s.count.Add(previous)
+}
+
+// Write grava um registro com falha
+func (s *Sink) Write(ctx context.Context, entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	s.count.Add(1)
+
+	if s.collection != nil {
+		if _, err := s.collection.InsertOne(ctx, entry); err != nil {
+			return fmt.Errorf("erro ao gravar dead-letter: %v", err)
+		}
+		return nil
+	}
+
+	line, err := bson.MarshalExtJSON(entry, false, false)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar dead-letter: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("erro ao abrir arquivo de dead-letter: %v", err)
+		}
+		s.file = file
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("erro ao gravar dead-letter: %v", err)
+	}
+	return nil
+}
+
+// Close fecha o arquivo de dead-letter, se houver
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		return s.file.Close()
+	}
+	return nil
+}
+
+// SourceRow monta a linha de origem com os nomes das colunas, preservando a ordem
+func SourceRow(columns []string, values []interface{}) bson.D {
+	row := make(bson.D, 0, len(columns))
+	for i, column := range columns {
+		if i >= len(values) {
+			break
+		}
+		value := values[i]
+		// Bytes válidos em UTF-8 são gravados como texto para facilitar a análise
+		if b, ok := value.([]byte); ok && utf8.Valid(b) {
+			value = string(b)
+		}
+		row = append(row, bson.E{Key: column, Value: value})
+	}
+	return row
+}
@@ -0,0 +1,85 @@
+package deadletter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFileSinkCreatesFileOnFirstWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "dead_letter.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("arquivo criado antes da primeira falha: %v", err)
+	}
+	if !strings.Contains(sink.Destination(), path) {
+		t.Errorf("Destination() = %q, esperado o caminho do arquivo", sink.Destination())
+	}
+
+	for _, id := range []int{1, 2} {
+		entry := Entry{WorkerID: id, Stage: StageInsert, Error: "falha", SourceRow: bson.D{{Key: "cpf", Value: "1"}}}
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || sink.Count() != 2 {
+		t.Fatalf("arquivo com %d linhas e Count() = %d, esperados 2", len(lines), sink.Count())
+	}
+	if !strings.Contains(lines[0], `"source_row":{"cpf":"1"}`) {
+		t.Errorf("linha = %s", lines[0])
+	}
+}
+
+func TestSourceRow(t *testing.T) {
+	row := SourceRow([]string{"cpf", "foto", "idade"}, []interface{}{[]byte("123"), []byte{0xff, 0xfe}, int64(30)})
+	want := bson.D{{Key: "cpf", Value: "123"}, {Key: "foto", Value: []byte{0xff, 0xfe}}, {Key: "idade", Value: int64(30)}}
+	if len(row) != len(want) {
+		t.Fatalf("SourceRow() = %v, esperado %v", row, want)
+	}
+	for i := range want {
+		if row[i].Key != want[i].Key {
+			t.Errorf("coluna %d = %q, esperado %q", i, row[i].Key, want[i].Key)
+		}
+	}
+	if row[0].Value != "123" {
+		t.Errorf("texto válido = %#v, esperado string", row[0].Value)
+	}
+	if _, ok := row[1].Value.([]byte); !ok {
+		t.Errorf("binário = %#v, esperado []byte", row[1].Value)
+	}
+}
+
+func TestResumeAddsPreviousFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letter.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	sink.Resume(3)
+	if err := sink.Write(context.Background(), Entry{Stage: StageInsert, Error: "falha"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.Count(); got != 4 {
+		t.Fatalf("Count() = %d, esperado 4", got)
+	}
+}
@@ -0,0 +1,289 @@
+package duplicates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"MysqlToMongo/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Estratégias de tratamento de CPFs duplicados
+const (
+	StrategyKeepFirst        = "keep_first"
+	StrategyKeepNewest       = "keep_newest"
+	StrategyMergeContacts    = "merge_contacts"
+	StrategyMoveToCollection = "move_to_collection"
+)
+
+// Código de erro do MongoDB para violação de índice único
+const duplicateKeyCode = 11000
+
+// Número máximo de tentativas quando outro worker altera o mesmo CPF ao mesmo tempo
+const maxReplaceAttempts = 5
+
+// CPFPartialFilter restringe o índice único aos CPFs preenchidos, para que documentos sem CPF
+// não colidam entre si nem sejam tratados como duplicados de outra pessoa
+var CPFPartialFilter = bson.D{{Key: "cpf", Value: bson.D{{Key: "$type", Value: "string"}, {Key: "$gt", Value: ""}}}}
+
+// ErrEmptyCPF indica um documento sem CPF rejeitado pelo índice único, que não é comparado com nenhum outro
+var ErrEmptyCPF = errors.New("documento sem CPF rejeitado pelo índice único de cpf; declare o índice com partial_filter para aceitá-lo")
+
+// Document representa um documento convertido que permite acessar campos pelo nome
+type Document interface {
+	Field(name string) (any, bool)
+}
+
+// Stats representa os contadores do tratamento de duplicados
+type Stats struct {
+	Detected int64
+	Replaced int64
+	Merged   int64
+	Archived int64
+}
+
+// Resolver resolve conflitos de CPF detectados durante a inserção
+type Resolver struct {
+	strategy string
+	archive  *mongo.Collection
+
+	detected atomic.Int64
+	replaced atomic.Int64
+	merged   atomic.Int64
+	archived atomic.Int64
+}
+
+// Validate verifica a estratégia configurada sem acessar o MongoDB
+func Validate(cfg config.DuplicatesConfig) error {
+	switch cfg.Strategy {
+	case "", StrategyKeepFirst, StrategyKeepNewest, StrategyMergeContacts, StrategyMoveToCollection:
+		return nil
+	}
+	return fmt.Errorf("estratégia de duplicados desconhecida '%s'", cfg.Strategy)
+}
+
+// New cria o resolvedor de duplicados. Retorna nil se nenhuma estratégia foi configurada
+func New(cfg config.DuplicatesConfig, db *mongo.Database, mainCollection string) (*Resolver, error) {
+	if cfg.Strategy == "" {
+		return nil, nil
+	}
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Strategy == StrategyMoveToCollection && cfg.Collection == "" {
+		cfg.Collection = mainCollection + "_duplicates"
+	}
+
+	resolver := &Resolver{strategy: cfg.Strategy}
+	if cfg.Collection != "" {
+		resolver.archive = db.Collection(cfg.Collection)
+	}
+	return resolver, nil
+}
+
+// Strategy retorna a estratégia configurada
+func (r *Resolver) Strategy() string {
+	return r.strategy
+}
+
+// ArchiveCollection retorna o nome da collection de descartados, se houver
+func (r *Resolver) ArchiveCollection() string {
+	if r == nil || r.archive == nil {
+		return ""
+	}
+	return r.archive.Name()
+}
+
+// Stats retorna os contadores atuais
+func (r *Resolver) Stats() Stats {
+	return Stats{
+		Detected: r.detected.Load(),
+		Replaced: r.replaced.Load(),
+		Merged:   r.merged.Load(),
+		Archived: r.archived.Load(),
+	}
+}
+
+// UniqueIndex retorna o índice único de CPF usado quando nenhum índice de cpf é declarado
+func UniqueIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: "cpf", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(CPFPartialFilter),
+	}
+}
+
+// EnsureUniqueIndex cria o índice único de CPF antes da carga para detectar duplicados durante a inserção
+func EnsureUniqueIndex(ctx context.Context, collection *mongo.Collection, index mongo.IndexModel) error {
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("erro ao criar índice único de CPF: %v", err)
+	}
+	return nil
+}
+
+// IsDuplicateKey verifica se a falha de escrita foi causada pelo índice único de CPF
+func IsDuplicateKey(writeErr mongo.WriteError) bool {
+	return duplicateKeyOn(writeErr, "cpf")
+}
+
+// IsDuplicateID verifica se a falha de escrita foi causada por um _id já existente
+func IsDuplicateID(writeErr mongo.WriteError) bool {
+	return duplicateKeyOn(writeErr, "_id")
+}
+
+// duplicateKeyOn verifica se a falha é de chave duplicada no índice cuja chave é apenas o campo informado.
+// O índice é identificado pelo keyPattern que o servidor inclui no erro de escrita
+func duplicateKeyOn(writeErr mongo.WriteError, field string) bool {
+	if writeErr.Code != duplicateKeyCode {
+		return false
+	}
+	keyPattern, ok := writeErr.Raw.Lookup("keyPattern").DocumentOK()
+	if !ok {
+		return false
+	}
+	keys, err := keyPattern.Elements()
+	return err == nil && len(keys) == 1 && keys[0].Key() == field
+}
+
+// Resolve aplica a estratégia configurada a um documento cujo CPF já existe na collection.
+// Documentos sem CPF retornam ErrEmptyCPF sem consultar a collection
+func (r *Resolver) Resolve(ctx context.Context, collection *mongo.Collection, doc Document) error {
+	cpf, _ := doc.Field("cpf")
+	if emptyCPF(cpf) {
+		return ErrEmptyCPF
+	}
+	r.detected.Add(1)
+
+	switch r.strategy {
+	case StrategyKeepNewest:
+		return r.keepNewest(ctx, collection, cpf, doc)
+	case StrategyMergeContacts:
+		return r.mergeContacts(ctx, collection, cpf, doc)
+	}
+
+	// keep_first e move_to_collection mantêm o documento já gravado
+	return r.archiveDocument(ctx, doc)
+}
+
+// keepNewest mantém o documento com a data_atualizacao mais recente
+func (r *Resolver) keepNewest(ctx context.Context, collection *mongo.Collection, cpf any, doc Document) error {
+	incoming, _ := doc.Field("data_atualizacao")
+	incomingTime := toTime(incoming)
+
+	for attempt := 0; attempt < maxReplaceAttempts; attempt++ {
+		var existing bson.M
+		if err := collection.FindOne(ctx, bson.M{"cpf": cpf}).Decode(&existing); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				// O documento existente foi removido, então basta inserir
+				_, err = collection.InsertOne(ctx, doc)
+				return err
+			}
+			return fmt.Errorf("erro ao buscar CPF duplicado: %v", err)
+		}
+
+		existingTime := toTime(existing["data_atualizacao"])
+		if incomingTime == nil || (existingTime != nil && !incomingTime.After(*existingTime)) {
+			return r.archiveDocument(ctx, doc)
+		}
+
+		// O _id do documento existente é preservado na substituição
+		replacement, err := withoutID(doc)
+		if err != nil {
+			return err
+		}
+
+		// Substitui apenas se o documento não mudou desde a leitura
+		filter := bson.M{"_id": existing["_id"], "data_atualizacao": existing["data_atualizacao"]}
+		result, err := collection.ReplaceOne(ctx, filter, replacement)
+		if err != nil {
+			return fmt.Errorf("erro ao substituir CPF duplicado: %v", err)
+		}
+		if result.MatchedCount == 1 {
+			r.replaced.Add(1)
+			delete(existing, "_id")
+			return r.archiveDocument(ctx, existing)
+		}
+	}
+
+	return fmt.Errorf("CPF %v alterado concorrentemente após %d tentativas", cpf, maxReplaceAttempts)
+}
+
+// mergeContacts acrescenta telefones e emails do duplicado ao documento existente
+func (r *Resolver) mergeContacts(ctx context.Context, collection *mongo.Collection, cpf any, doc Document) error {
+	telefones, _ := doc.Field("contatos.telefones")
+	emails, _ := doc.Field("contatos.emails")
+
+	update := bson.M{"$addToSet": bson.M{
+		"contatos.telefones": bson.M{"$each": telefones},
+		"contatos.emails":    bson.M{"$each": emails},
+	}}
+	if _, err := collection.UpdateOne(ctx, bson.M{"cpf": cpf}, update); err != nil {
+		return fmt.Errorf("erro ao mesclar contatos do CPF duplicado: %v", err)
+	}
+	r.merged.Add(1)
+
+	return r.archiveDocument(ctx, doc)
+}
+
+// archiveDocument grava o documento descartado na collection de duplicados, se configurada
+func (r *Resolver) archiveDocument(ctx context.Context, doc any) error {
+	if r.archive == nil {
+		return nil
+	}
+	if _, err := r.archive.InsertOne(ctx, doc); err != nil {
+		// O mesmo _id indica que o documento já foi arquivado em uma tentativa anterior do chunk
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("erro ao gravar duplicado em '%s': %v", r.archive.Name(), err)
+	}
+	r.archived.Add(1)
+	return nil
+}
+
+// withoutID converte o documento para bson.D sem o campo _id
+func withoutID(doc any) (bson.D, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar documento: %v", err)
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("erro ao serializar documento: %v", err)
+	}
+
+	result := fields[:0]
+	for _, field := range fields {
+		if field.Key != "_id" {
+			result = append(result, field)
+		}
+	}
+	return result, nil
+}
+
+// toTime extrai a data de um campo convertido ou lido do MongoDB
+func toTime(value any) *time.Time {
+	switch v := value.(type) {
+	case *time.Time:
+		return v
+	case time.Time:
+		return &v
+	case primitive.DateTime:
+		t := v.Time()
+		return &t
+	}
+	return nil
+}
+
+// emptyCPF verifica se o CPF está ausente ou vazio
+func emptyCPF(cpf any) bool {
+	value, ok := cpf.(string)
+	return !ok || strings.TrimSpace(value) == ""
+}
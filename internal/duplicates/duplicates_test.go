@@ -0,0 +1,49 @@
+package duplicates
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// writeError monta a falha de escrita como o servidor a envia
+func writeError(t *testing.T, code int, keyPattern bson.D) mongo.WriteError {
+	t.Helper()
+	raw := bson.D{{Key: "index", Value: 0}, {Key: "code", Value: code}, {Key: "errmsg", Value: "E11000 duplicate key error"}}
+	if keyPattern != nil {
+		raw = append(raw, bson.E{Key: "keyPattern", Value: keyPattern})
+	}
+	data, err := bson.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mongo.WriteError{Code: code, Message: "E11000 duplicate key error", Raw: data}
+}
+
+func TestDuplicateKind(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       int
+		keyPattern bson.D
+		wantID     bool
+		wantKey    bool
+	}{
+		{"_id", duplicateKeyCode, bson.D{{Key: "_id", Value: 1}}, true, false},
+		{"cpf", duplicateKeyCode, bson.D{{Key: "cpf", Value: 1}}, false, true},
+		{"índice composto", duplicateKeyCode, bson.D{{Key: "cpf", Value: 1}, {Key: "_id", Value: 1}}, false, false},
+		{"outro índice único", duplicateKeyCode, bson.D{{Key: "email", Value: 1}}, false, false},
+		{"sem keyPattern", duplicateKeyCode, nil, false, false},
+		{"outro código", 121, bson.D{{Key: "_id", Value: 1}}, false, false},
+	}
+
+	for _, tt := range tests {
+		writeErr := writeError(t, tt.code, tt.keyPattern)
+		if got := IsDuplicateID(writeErr); got != tt.wantID {
+			t.Errorf("%s: IsDuplicateID() = %v, esperado %v", tt.name, got, tt.wantID)
+		}
+		if got := IsDuplicateKey(writeErr); got != tt.wantKey {
+			t.Errorf("%s: IsDuplicateKey() = %v, esperado %v", tt.name, got, tt.wantKey)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"MysqlToMongo/internal/rules"
+	"MysqlToMongo/internal/search"
+)
+
+// Número de registros lidos para sugerir conversores quando não informado
+const DefaultSample = 200
+
+// Column representa uma coluna da tabela de origem com o conversor sugerido
+type Column struct {
+	Position   int    // Posição da coluna (a partir de 1), como usada no mapeamento
+	Name       string // Nome no MySQL
+	Field      string // Nome sugerido para o campo, em snake_case
+	DataType   string // Tipo base, ex.: varchar
+	ColumnType string // Tipo completo, ex.: varchar(11)
+	Nullable   bool
+	Converter  string   // Conversor sugerido pelo tipo e pelos valores lidos
+	Notes      []string // Observações sobre os valores lidos
+	MappedTo   string   // Campo do mapeamento que usa a coluna, vazio se nenhum
+
+	sample sampleStats
+	kind   string // cpf, email ou phone, quando a maior parte dos valores lidos parece um deles
+}
+
+// sampleStats resume os valores lidos de uma coluna
+type sampleStats struct {
+	values int // Valores não nulos e não vazios
+	dates  int // AAAAMMDD válidos
+	base64 int // Base64 que decodifica para texto UTF-8
+	cpfs   int // CPFs válidos, só com dígitos ou no formato 000.000.000-00
+	phones int // Telefones com DDD, com ou sem pontuação
+	emails int // Contêm @ e ponto no domínio
+	zeros  int // "0"
+}
+
+// Tipos de conteúdo reconhecidos nos valores lidos
+const (
+	kindCPF   = "cpf"
+	kindEmail = "email"
+	kindPhone = "phone"
+)
+
+// Padrões usados na análise dos valores
+var (
+	digitsPattern = regexp.MustCompile(`^\d+$`)
+	cpfPattern    = regexp.MustCompile(`^\d{3}\.\d{3}\.\d{3}-\d{2}$`)
+	phonePattern  = regexp.MustCompile(`^\(?\d{2}\)?[\s-]?\d{4,5}-?\d{4}$|^\d{10,13}$`) // Sem DDD seria confundido com o CEP
+	emailPattern  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// Columns lê as colunas da tabela em INFORMATION_SCHEMA.COLUMNS, na ordem do SELECT *
+func Columns(ctx context.Context, db *sql.DB, database, table string) ([]*Column, error) {
+	rows, err := db.QueryContext(ctx, `SELECT COLUMN_NAME, ORDINAL_POSITION, DATA_TYPE, COLUMN_TYPE, IS_NULLABLE
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar as colunas: %v", err)
+	}
+	defer rows.Close()
+
+	var columns []*Column
+	for rows.Next() {
+		var c Column
+		var nullable string
+		if err := rows.Scan(&c.Name, &c.Position, &c.DataType, &c.ColumnType, &nullable); err != nil {
+			return nil, err
+		}
+		c.DataType = strings.ToLower(c.DataType)
+		c.Nullable = nullable == "YES"
+		c.Field = SnakeCase(c.Name)
+		columns = append(columns, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("tabela '%s.%s' não encontrada ou sem colunas", database, table)
+	}
+	return columns, nil
+}
+
+// Analyze lê até sample registros da tabela e sugere o conversor de cada coluna
+func Analyze(ctx context.Context, db *sql.DB, table string, columns []*Column, sample int) error {
+	if sample <= 0 {
+		sample = DefaultSample
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT ?", table), sample)
+	if err != nil {
+		return fmt.Errorf("erro ao ler a amostra: %v", err)
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(names) != len(columns) {
+		return fmt.Errorf("a consulta retornou %d colunas, INFORMATION_SCHEMA informa %d", len(names), len(columns))
+	}
+	values := make([]interface{}, len(names))
+	valuePtrs := make([]interface{}, len(names))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return err
+		}
+		for i, value := range values {
+			columns[i].sample.observe(value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		c.suggest()
+	}
+	return nil
+}
+
+// observe registra um valor lido da coluna
+func (s *sampleStats) observe(value interface{}) {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	default:
+		str = fmt.Sprint(v)
+	}
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return
+	}
+
+	s.values++
+	if str == "0" {
+		s.zeros++
+	}
+	if len(str) == 8 && digitsPattern.MatchString(str) {
+		if _, err := time.Parse("20060102", str); err == nil {
+			s.dates++
+		}
+	}
+	// Celulares com DDD também têm 11 dígitos; os dígitos verificadores separam os CPFs
+	if cpf, ok := cpfDigits(str); ok && rules.ValidCPF(cpf) {
+		s.cpfs++
+	}
+	if phonePattern.MatchString(str) {
+		s.phones++
+	}
+	if emailPattern.MatchString(str) {
+		s.emails++
+	}
+	if isBase64Text(str) {
+		s.base64++
+	}
+}
+
+// cpfDigits retorna os 11 dígitos do CPF formatado ou guardado como número, sem os zeros à esquerda
+func cpfDigits(str string) (string, bool) {
+	if cpfPattern.MatchString(str) {
+		return strings.NewReplacer(".", "", "-", "").Replace(str), true
+	}
+	if len(str) >= 9 && len(str) <= 11 && digitsPattern.MatchString(str) {
+		return strings.Repeat("0", 11-len(str)) + str, true
+	}
+	return "", false
+}
+
+// isBase64Text indica se o valor parece texto codificado em base64, como gravado em algumas colunas BLOB
+func isBase64Text(str string) bool {
+	if len(str) < 8 || len(str)%4 != 0 || digitsPattern.MatchString(str) {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(str)
+	if err != nil || !utf8.Valid(decoded) {
+		return false
+	}
+	for _, r := range string(decoded) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// mostly indica se ao menos 90% dos valores lidos atendem ao critério
+func (s *sampleStats) mostly(count int) bool {
+	return s.values > 0 && count*10 >= s.values*9
+}
+
+// suggest escolhe o conversor pelo tipo da coluna e pelos valores lidos
+func (c *Column) suggest() {
+	s := &c.sample
+	switch c.DataType {
+	case "date", "datetime", "timestamp":
+		c.Converter = "ConvertToTimePtr"
+	case "decimal", "float", "double":
+		c.Converter = "ConvertToDecimal"
+	case "tinyint", "smallint", "mediumint", "int", "bigint":
+		c.Converter = "ConvertOptionalField"
+		if s.zeros > 0 {
+			c.Notes = append(c.Notes, "zero vira null")
+		}
+	default:
+		c.Converter = "ConvertBinaryToString"
+		switch {
+		case s.mostly(s.dates):
+			c.Converter = "ConvertToDatePtr"
+			c.Notes = append(c.Notes, "data em texto AAAAMMDD")
+		case s.mostly(s.base64):
+			c.Notes = append(c.Notes, "texto em base64, decodificado pelo conversor")
+		case s.zeros > 0:
+			c.Converter = "ConvertOptionalField"
+			c.Notes = append(c.Notes, "contém \"0\", tratado como vazio")
+		}
+	}
+
+	switch {
+	case s.values == 0:
+		c.Notes = append(c.Notes, "sem valores na amostra")
+	case s.mostly(s.cpfs):
+		c.kind = kindCPF
+		c.Notes = append(c.Notes, "parece CPF")
+	case s.mostly(s.emails):
+		c.kind = kindEmail
+		c.Notes = append(c.Notes, "parece email")
+	case s.mostly(s.phones) && !s.mostly(s.dates):
+		c.kind = kindPhone
+		c.Notes = append(c.Notes, "parece telefone")
+	}
+}
+
+// SnakeCase converte o nome da coluna para snake_case sem acentos: "DataNascimento" e "Data Nascimento"
+// viram "data_nascimento" e "CPFConjuge" vira "cpf_conjuge"
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(strings.ReplaceAll(search.Normalize(b.String()), " ", "_"))
+}
@@ -0,0 +1,113 @@
+package introspect
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"DataNascimento":  "data_nascimento",
+		"Data Nascimento": "data_nascimento",
+		"CPFConjuge":      "cpf_conjuge",
+		"nome_mãe":        "nome_mae",
+		"Telefone1":       "telefone1",
+		"UF":              "uf",
+	}
+	for name, want := range tests {
+		if got := SnakeCase(name); got != want {
+			t.Errorf("SnakeCase(%q) = %q, esperado %q", name, got, want)
+		}
+	}
+}
+
+// column monta uma coluna com os valores lidos na amostra
+func column(position int, name, dataType string, values ...interface{}) *Column {
+	c := &Column{Position: position, Name: name, Field: SnakeCase(name), DataType: dataType}
+	for _, value := range values {
+		c.sample.observe(value)
+	}
+	c.suggest()
+	return c
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name          string
+		column        *Column
+		wantConverter string
+		wantKind      string
+	}{
+		{"data", column(1, "dt", "datetime"), "ConvertToTimePtr", ""},
+		{"decimal", column(1, "renda", "decimal"), "ConvertToDecimal", ""},
+		{"data em texto", column(1, "nasc", "varchar", []byte("19800501"), []byte("19991231")), "ConvertToDatePtr", ""},
+		{"texto com zeros", column(1, "bairro", "varchar", []byte("Centro"), []byte("0")), "ConvertOptionalField", ""},
+		{"base64", column(1, "nome", "blob", []byte("TWFyaWEgU2lsdmE=")), "ConvertBinaryToString", ""},
+		{"cpf", column(1, "documento", "varchar", []byte("529.982.247-25"), []byte("11144477735")), "ConvertBinaryToString", kindCPF},
+		{"email", column(1, "contato", "varchar", []byte("maria@email.com")), "ConvertBinaryToString", kindEmail},
+		{"telefone", column(1, "contato", "varchar", []byte("(31) 99632-0718"), []byte("3133334444")), "ConvertBinaryToString", kindPhone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.column.Converter != tt.wantConverter || tt.column.kind != tt.wantKind {
+				t.Errorf("conversor %s e conteúdo %q, esperado %s e %q (%v)",
+					tt.column.Converter, tt.column.kind, tt.wantConverter, tt.wantKind, tt.column.Notes)
+			}
+		})
+	}
+}
+
+func TestPropose(t *testing.T) {
+	columns := []*Column{
+		column(1, "id", "int", int64(1)),
+		column(2, "Documento", "varchar", []byte("52998224725")),
+		column(3, "NomeCompleto", "varchar", []byte("Maria")),
+		column(4, "DtNascimento", "date"),
+		column(5, "Email", "varchar", []byte("maria@email.com")),
+		column(6, "Celular", "varchar", []byte("31996320718")),
+		column(7, "Contato2", "varchar", []byte("(31) 3333-4444")),
+		column(8, "Municipio", "varchar", []byte("Belo Horizonte")),
+	}
+
+	mapping, unmapped := Propose(columns)
+	p := mapping.Pessoas
+	if p.CPF != 2 || p.Nome != 3 || p.Nasc != 4 || p.Cidade != 8 {
+		t.Errorf("cpf %d, nome %d, nasc %d e cidade %d; esperado 2, 3, 4 e 8", p.CPF, p.Nome, p.Nasc, p.Cidade)
+	}
+	if !reflect.DeepEqual(p.Contatos.Telefones, []int{6, 7}) || !reflect.DeepEqual(p.Contatos.Emails, []int{5}) {
+		t.Errorf("telefones %v e emails %v, esperado [6 7] e [5]", p.Contatos.Telefones, p.Contatos.Emails)
+	}
+	if columns[1].MappedTo != "cpf" || columns[6].MappedTo != "contatos.telefones[1]" || columns[0].MappedTo != "" {
+		t.Errorf("colunas mapeadas em %q, %q e %q", columns[1].MappedTo, columns[6].MappedTo, columns[0].MappedTo)
+	}
+	// O conversor sugerido pela coluna difere do usado pelo campo
+	if !strings.Contains(strings.Join(columns[3].Notes, ";"), "ConvertToDatePtr") {
+		t.Errorf("nasc sem a observação do conversor do campo: %v", columns[3].Notes)
+	}
+	for _, field := range []string{"renda", "uf", "data_atualizacao"} {
+		if !contains(unmapped, field) {
+			t.Errorf("%s deveria estar entre os campos sem coluna: %v", field, unmapped)
+		}
+	}
+	if contains(unmapped, "contatos.telefones") || contains(unmapped, "cpf") {
+		t.Errorf("campos mapeados entre os sem coluna: %v", unmapped)
+	}
+
+	var report bytes.Buffer
+	WriteReport(&report, "pessoas", columns, unmapped)
+	if !strings.Contains(report.String(), "Tabela pessoas: 8 colunas") || !strings.Contains(report.String(), "preencha manualmente") {
+		t.Errorf("relatório incompleto:\n%s", report.String())
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package introspect
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/models"
+)
+
+// Outros nomes comuns das colunas de cada campo do mapeamento, já em snake_case
+var aliases = map[string][]string{
+	"cpf":              {"nu_cpf", "num_cpf", "numero_cpf", "cpf_cnpj"},
+	"nome":             {"nome_completo", "nm_pessoa", "nm_nome", "name"},
+	"nasc":             {"nascimento", "data_nascimento", "data_nasc", "dt_nascimento", "dt_nasc"},
+	"renda":            {"renda_presumida", "vl_renda", "salario"},
+	"sexo":             {"genero", "sx"},
+	"mae":              {"nome_mae", "nm_mae"},
+	"cpf_conjuge":      {"nu_cpf_conjuge", "conjuge_cpf"},
+	"serv_publico":     {"servidor_publico", "fl_servidor"},
+	"data_obito":       {"dt_obito", "obito"},
+	"cidade":           {"municipio", "nm_cidade"},
+	"endereco":         {"logradouro", "end"},
+	"bairro":           {"nm_bairro"},
+	"cep":              {"nu_cep"},
+	"uf":               {"estado", "sg_uf"},
+	"data_atualizacao": {"dt_atualizacao", "atualizado_em", "updated_at", "dt_alteracao"},
+}
+
+// Trechos do nome que indicam colunas de telefone e de email
+var (
+	phoneNames = []string{"tel", "fone", "celular"}
+	emailNames = []string{"email", "e_mail"}
+)
+
+// Propose monta o mapeamento sugerido a partir das colunas analisadas: primeiro pelo nome da coluna,
+// depois pelo conteúdo (CPFs, telefones e emails). Retorna também os campos que ficaram sem coluna
+func Propose(columns []*Column) (*config.MappingConfig, []string) {
+	mapping := &config.MappingConfig{}
+	mapping.Pessoas.Contatos.Telefones = []int{}
+	mapping.Pessoas.Contatos.Emails = []int{}
+	fields := reflect.ValueOf(&mapping.Pessoas).Elem()
+
+	var unmapped []string
+	for _, m := range models.FieldMappings(&config.MappingConfig{}) {
+		if strings.HasPrefix(m.Field, "contatos.") {
+			continue
+		}
+		c := findByName(columns, append([]string{m.Field}, aliases[m.Field]...))
+		if c == nil && m.Field == "cpf" {
+			c = findByKind(columns, kindCPF)
+		}
+		if c == nil {
+			unmapped = append(unmapped, m.Field)
+			continue
+		}
+		setPosition(fields, m.Field, c.Position)
+		c.MappedTo = m.Field
+		if c.Converter != "" && c.Converter != m.Converter {
+			c.Notes = append(c.Notes, fmt.Sprintf("o campo %s usa %s", m.Field, m.Converter))
+		}
+	}
+
+	// Contatos: todas as colunas restantes com nome ou conteúdo de telefone e de email, na ordem da tabela
+	for _, c := range columns {
+		if c.MappedTo != "" {
+			continue
+		}
+		switch {
+		case containsAny(c.Field, emailNames) || c.kind == kindEmail:
+			c.MappedTo = fmt.Sprintf("contatos.emails[%d]", len(mapping.Pessoas.Contatos.Emails))
+			mapping.Pessoas.Contatos.Emails = append(mapping.Pessoas.Contatos.Emails, c.Position)
+		case containsAny(c.Field, phoneNames) || c.kind == kindPhone:
+			c.MappedTo = fmt.Sprintf("contatos.telefones[%d]", len(mapping.Pessoas.Contatos.Telefones))
+			mapping.Pessoas.Contatos.Telefones = append(mapping.Pessoas.Contatos.Telefones, c.Position)
+		}
+	}
+	if len(mapping.Pessoas.Contatos.Telefones) == 0 {
+		unmapped = append(unmapped, "contatos.telefones")
+	}
+	if len(mapping.Pessoas.Contatos.Emails) == 0 {
+		unmapped = append(unmapped, "contatos.emails")
+	}
+	return mapping, unmapped
+}
+
+// findByName retorna a primeira coluna ainda não mapeada com um dos nomes
+func findByName(columns []*Column, names []string) *Column {
+	for _, name := range names {
+		for _, c := range columns {
+			if c.MappedTo == "" && c.Field == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// findByKind retorna a primeira coluna ainda não mapeada cujo conteúdo é do tipo informado
+func findByKind(columns []*Column, kind string) *Column {
+	for _, c := range columns {
+		if c.MappedTo == "" && c.kind == kind {
+			return c
+		}
+	}
+	return nil
+}
+
+// containsAny indica se o nome contém algum dos trechos
+func containsAny(name string, parts []string) bool {
+	for _, part := range parts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// setPosition grava a posição no campo do mapeamento com o nome usado no JSON
+func setPosition(fields reflect.Value, name string, position int) {
+	for i := 0; i < fields.NumField(); i++ {
+		tag, _, _ := strings.Cut(fields.Type().Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			fields.Field(i).SetInt(int64(position))
+			return
+		}
+	}
+}
+
+// WriteReport mostra cada coluna com o campo sugerido, o conversor e onde foi mapeada, seguida dos campos sem coluna
+func WriteReport(w io.Writer, table string, columns []*Column, unmapped []string) {
+	fmt.Fprintf(w, "Tabela %s: %d colunas\n\n", table, len(columns))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tColuna\tTipo\tCampo sugerido\tConversor sugerido\tMapeada em\tObservações")
+	for _, c := range columns {
+		mappedTo := c.MappedTo
+		if mappedTo == "" {
+			mappedTo = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Position, c.Name, c.ColumnType, c.Field, c.Converter, mappedTo, strings.Join(c.Notes, "; "))
+	}
+	tw.Flush()
+
+	if len(unmapped) > 0 {
+		fmt.Fprintf(w, "\nCampos sem coluna correspondente (preencha manualmente): %s\n", strings.Join(unmapped, ", "))
+	}
+}
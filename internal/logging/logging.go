@@ -0,0 +1,295 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level representa o nível mínimo das mensagens registradas
+type Level int32
+
+// Níveis de log, do mais detalhado ao mais restrito
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Nomes aceitos em --log-level e general.log_level
+var levelNames = map[string]Level{
+	"debug": LevelDebug,
+	"info":  LevelInfo,
+	"warn":  LevelWarn,
+	"error": LevelError,
+}
+
+// Níveis correspondentes do log/slog
+var slogLevels = map[Level]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
+
+// Formatos aceitos em --log-format e general.log_format
+const (
+	FormatText = "text" // Uma linha legível por mensagem, como antes do log estruturado
+	FormatJSON = "json" // Um objeto JSON por mensagem, com os campos separados
+)
+
+// Nível atual, compartilhado pelos handlers; o padrão é info
+var minLevel slog.LevelVar
+
+// Configuração da saída, alterada por SetFormat, SetOutput e SetAttrs
+var (
+	mu     sync.Mutex
+	format string    = FormatText
+	out    io.Writer = os.Stderr
+	attrs  []any
+)
+
+// Logger usado pelas funções do pacote, recriado a cada mudança na configuração
+var (
+	current    atomic.Pointer[slog.Logger]
+	jsonOutput atomic.Bool
+)
+
+func init() {
+	rebuild()
+}
+
+// ParseLevel converte o nome do nível. Vazio é tratado como info
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LevelInfo, nil
+	}
+	level, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return LevelInfo, fmt.Errorf("nível de log inválido: %q (use debug, info, warn ou error)", name)
+	}
+	return level, nil
+}
+
+// SetLevel define o nível mínimo das mensagens registradas
+func SetLevel(l Level) {
+	minLevel.Set(slogLevels[l])
+}
+
+// Enabled indica se as mensagens do nível são registradas
+func Enabled(l Level) bool {
+	return slogLevels[l] >= minLevel.Level()
+}
+
+// ParseFormat valida o formato do log. Vazio é tratado como text
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("formato de log inválido: %q (use %s ou %s)", name, FormatText, FormatJSON)
+}
+
+// SetFormat define o formato das mensagens: text ou json
+func SetFormat(name string) error {
+	f, err := ParseFormat(name)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	rebuild()
+	return nil
+}
+
+// SetOutput define onde as mensagens são escritas; o padrão é os.Stderr.
+// Os valores registrados em Redact são ocultados
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+	rebuild()
+}
+
+// SetAttrs define os campos incluídos em todas as mensagens no formato JSON, como job e run_id.
+// No formato texto eles já fazem parte do nome do arquivo de log e não são repetidos em cada linha
+func SetAttrs(args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	attrs = args
+	rebuild()
+}
+
+// rebuild cria o logger com a configuração atual. Deve ser chamada com mu travado, exceto em init
+func rebuild() {
+	w := Writer(out)
+	jsonOutput.Store(format == FormatJSON)
+	if format == FormatJSON {
+		current.Store(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &minLevel})).With(attrs...))
+		return
+	}
+	current.Store(slog.New(newTextHandler(w, &minLevel)))
+}
+
+// Identificador desta execução, gerado uma única vez
+var (
+	runIDOnce sync.Once
+	runID     string
+)
+
+// RunID retorna o identificador desta execução, usado nos nomes dos arquivos de log e no campo run_id,
+// para que execuções iniciadas no mesmo minuto não compartilhem arquivos
+func RunID() string {
+	runIDOnce.Do(func() {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			// Sem fonte aleatória, o horário com nanossegundos ainda distingue as execuções
+			runID = fmt.Sprintf("%08x", uint32(time.Now().UnixNano()))
+			return
+		}
+		runID = hex.EncodeToString(b)
+	})
+	return runID
+}
+
+// Logger registra mensagens com campos fixos, como worker_id e chunk, que aparecem separados no formato JSON
+// e ao final da linha no formato texto
+type Logger struct {
+	args []any
+}
+
+// Logger sem campos, usado pelas funções do pacote
+var std = &Logger{}
+
+// With retorna um Logger que inclui os campos informados, em pares nome e valor
+func With(args ...any) *Logger {
+	return std.With(args...)
+}
+
+// With retorna um Logger com os campos deste e os informados
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{args: append(l.args[:len(l.args):len(l.args)], args...)}
+}
+
+// Debugf registra uma mensagem de diagnóstico, mostrada apenas com --log-level debug
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.output(LevelDebug, format, args...)
+}
+
+// Infof registra uma mensagem informativa
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.output(LevelInfo, format, args...)
+}
+
+// Warnf registra um aviso
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.output(LevelWarn, format, args...)
+}
+
+// Errorf registra um erro que não interrompe a execução
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.output(LevelError, format, args...)
+}
+
+// output formata e registra a mensagem se o nível estiver habilitado
+func (l *Logger) output(lvl Level, format string, args ...interface{}) {
+	if Enabled(lvl) {
+		l.log(lvl, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *Logger) log(lvl Level, msg string) {
+	current.Load().Log(context.Background(), slogLevels[lvl], msg, l.args...)
+}
+
+// Debugf registra uma mensagem de diagnóstico, mostrada apenas com --log-level debug
+func Debugf(format string, args ...interface{}) {
+	std.output(LevelDebug, format, args...)
+}
+
+// Infof registra uma mensagem informativa
+func Infof(format string, args ...interface{}) {
+	std.output(LevelInfo, format, args...)
+}
+
+// Info registra os valores como log.Println. Linhas em branco, usadas para separar blocos no console,
+// são omitidas no formato JSON
+func Info(args ...interface{}) {
+	if !Enabled(LevelInfo) {
+		return
+	}
+	msg := strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	if msg == "" && jsonOutput.Load() {
+		return
+	}
+	std.log(LevelInfo, msg)
+}
+
+// Warnf registra um aviso
+func Warnf(format string, args ...interface{}) {
+	std.output(LevelWarn, format, args...)
+}
+
+// Errorf registra um erro que não interrompe a execução
+func Errorf(format string, args ...interface{}) {
+	std.output(LevelError, format, args...)
+}
+
+// Fatalf registra o erro e encerra o programa com código 1
+func Fatalf(format string, args ...interface{}) {
+	std.log(LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// Substitui as senhas registradas em Redact; nil enquanto não houver nenhuma
+var redactor atomic.Pointer[strings.Replacer]
+
+// Redact registra valores, como senhas da configuração, que são substituídos por *** em tudo o que
+// passa por Writer, incluindo as mensagens de erro dos drivers
+func Redact(secrets ...string) {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, "***")
+		}
+	}
+	if len(pairs) == 0 {
+		redactor.Store(nil)
+		return
+	}
+	redactor.Store(strings.NewReplacer(pairs...))
+}
+
+// Writer retorna um io.Writer que oculta os valores registrados em Redact antes de escrever em w.
+// Usado como saída dos handlers e do pacote log
+func Writer(w io.Writer) io.Writer {
+	return redactingWriter{w}
+}
+
+type redactingWriter struct {
+	w io.Writer
+}
+
+// Write oculta os valores registrados. Os handlers e o pacote log escrevem cada mensagem em uma única chamada
+func (r redactingWriter) Write(p []byte) (int, error) {
+	replacer := redactor.Load()
+	if replacer == nil {
+		return r.w.Write(p)
+	}
+	if _, err := r.w.Write([]byte(replacer.Replace(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"MysqlToMongo/internal/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Endereço padrão do endpoint /metrics
+const DefaultAddr = ":2112"
+
+// Prefixo dos nomes das métricas
+const namespace = "mtm"
+
+// Estágios do pipeline usados no rótulo stage de mtm_workers
+const (
+	StageReader = "reader"
+	StageWriter = "writer"
+)
+
+// Estados dos workers usados no rótulo state de mtm_workers
+const (
+	StateIdle      = "idle"      // Aguardando um chunk ou documentos
+	StateReading   = "reading"   // Lendo um chunk do MySQL
+	StateInserting = "inserting" // Inserindo um lote no MongoDB
+)
+
+var (
+	// RowsRead conta as linhas lidas do MySQL e enviadas para a conversão
+	RowsRead = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_read_total",
+		Help:      "Linhas lidas do MySQL.",
+	})
+
+	// DocumentsWritten conta os documentos gravados por collection de destino
+	DocumentsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "documents_written_total",
+		Help:      "Documentos gravados no MongoDB, por collection.",
+	}, []string{"collection"})
+
+	// ConversionFailures conta os valores preenchidos na origem que o conversor deixou vazios, por campo
+	ConversionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conversion_failures_total",
+		Help:      "Valores preenchidos na origem que o conversor não conseguiu converter, por campo.",
+	}, []string{"field"})
+
+	// DeadLetters conta os registros enviados ao dead-letter, por etapa (conversion ou insert)
+	DeadLetters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_letter_total",
+		Help:      "Registros enviados ao dead-letter, por etapa.",
+	}, []string{"stage"})
+
+	// BatchInsertDuration mede a inserção de cada lote no MongoDB, incluindo novas tentativas
+	BatchInsertDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_insert_duration_seconds",
+		Help:      "Duração da inserção de cada lote no MongoDB.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+
+	// MySQLQueryDuration mede a abertura do cursor de cada chunk no MySQL, até a primeira linha estar disponível
+	MySQLQueryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mysql_query_duration_seconds",
+		Help:      "Duração da consulta de cada chunk no MySQL até o cursor ser aberto.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+
+	// Workers conta os workers de cada estágio em cada estado
+	Workers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers",
+		Help:      "Workers de cada estágio do pipeline em cada estado.",
+	}, []string{"stage", "state"})
+
+	// QueueDepth informa quantos itens aguardam em cada fila do pipeline (rows ou documents)
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Itens aguardando em cada fila do pipeline.",
+	}, []string{"queue"})
+
+	// QueueCapacity informa a capacidade de cada fila do pipeline
+	QueueCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_capacity",
+		Help:      "Capacidade de cada fila do pipeline.",
+	}, []string{"queue"})
+
+	// RecordsTotal informa o total de registros da tabela de origem
+	RecordsTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "records_total",
+		Help:      "Registros da tabela de origem.",
+	})
+
+	// RecordsProcessed informa os registros concluídos, incluindo os de uma execução retomada
+	RecordsProcessed = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "records_processed",
+		Help:      "Registros gravados, descartados pelas regras ou enviados ao dead-letter.",
+	})
+
+	// EstimatedRemaining informa o tempo restante estimado pela velocidade média
+	EstimatedRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "estimated_remaining_seconds",
+		Help:      "Tempo restante estimado da migração.",
+	})
+)
+
+// Registro com as métricas da migração e do processo
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		RowsRead, DocumentsWritten, ConversionFailures, DeadLetters,
+		BatchInsertDuration, MySQLQueryDuration,
+		Workers, QueueDepth, QueueCapacity,
+		RecordsTotal, RecordsProcessed, EstimatedRemaining,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// SetWorkerState move um worker do estado from para o estado to. Com from vazio o worker está começando;
+// com to vazio, terminando
+func SetWorkerState(stage, from, to string) {
+	if from != "" {
+		Workers.WithLabelValues(stage, from).Dec()
+	}
+	if to != "" {
+		Workers.WithLabelValues(stage, to).Inc()
+	}
+}
+
+// Handler retorna o handler HTTP do endpoint /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve expõe /metrics em addr. O endereço é aberto antes de retornar, para que erros como porta em uso
+// sejam informados de imediato; a função retornada encerra o servidor
+func Serve(addr string) (stop func(), err error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir o endpoint de métricas em %s: %v", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Errorf("Erro no endpoint de métricas: %v", err)
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetWorkerState(t *testing.T) {
+	Workers.Reset()
+	SetWorkerState(StageReader, "", StateIdle)
+	SetWorkerState(StageReader, "", StateIdle)
+	SetWorkerState(StageReader, StateIdle, StateReading)
+
+	if got := testutil.ToFloat64(Workers.WithLabelValues(StageReader, StateIdle)); got != 1 {
+		t.Errorf("leitores ociosos = %v, esperado 1", got)
+	}
+	if got := testutil.ToFloat64(Workers.WithLabelValues(StageReader, StateReading)); got != 1 {
+		t.Errorf("leitores lendo = %v, esperado 1", got)
+	}
+
+	SetWorkerState(StageReader, StateReading, "")
+	if got := testutil.ToFloat64(Workers.WithLabelValues(StageReader, StateReading)); got != 0 {
+		t.Errorf("leitores lendo após terminar = %v, esperado 0", got)
+	}
+}
+
+func TestServe(t *testing.T) {
+	// Escolhe uma porta livre para consultar o endpoint
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	stop, err := Serve(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// A porta em uso é informada antes da migração começar
+	if _, err := Serve(addr); err == nil {
+		t.Error("Serve() deveria falhar com a porta em uso")
+	}
+
+	RowsRead.Add(3)
+	DocumentsWritten.WithLabelValues("pessoas").Inc()
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"mtm_rows_read_total", `mtm_documents_written_total{collection="pessoas"}`, "go_goroutines", "process_"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics sem %s", want)
+		}
+	}
+}
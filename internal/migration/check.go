@@ -0,0 +1,58 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/models"
+	"MysqlToMongo/internal/rules"
+	"MysqlToMongo/internal/throttle"
+)
+
+// CheckConfig valida a configuração, o mapeamento, as regras de filtro e os índices declarados sem acessar
+// os bancos. Retorna um *config.ValidationError com todos os problemas encontrados
+func CheckConfig(cfg *config.Config) error {
+	problems := config.Validate(cfg)
+	problems = append(problems, models.CheckMapping(cfg.Mapping, nil)...)
+	if _, err := rules.New(cfg.Rules, &models.OrderedDocument{}); err != nil {
+		problems.Add("erro nas regras de filtro: %v", err)
+	}
+	if err := duplicates.Validate(cfg.Duplicates); err != nil {
+		problems.Add("duplicates: %v", err)
+	} else if cfg.Duplicates.Strategy != "" {
+		if _, err := duplicatesIndex(cfg); err != nil {
+			problems.Add("duplicates: %v", err)
+		}
+	}
+	if err := throttle.Validate(cfg.Throttle); err != nil {
+		problems.Add("throttle: %v", err)
+	}
+	if _, err := declaredIndexes(cfg); err != nil {
+		problems.Add("erro na declaração dos índices: %v", err)
+	}
+	return problems.Err()
+}
+
+// CheckSource verifica, já conectado ao MySQL, se a tabela de origem existe, tem todas as colunas
+// referenciadas no mapeamento e uma chave inteira para a leitura em ordem
+func CheckSource(ctx context.Context, cfg *config.Config, mysqlDB *sql.DB) error {
+	// Mesmas colunas, na mesma ordem, do SELECT * usado pelos leitores
+	rows, err := mysqlDB.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", cfg.MySQL.Table))
+	if err != nil {
+		return fmt.Errorf("erro ao consultar a tabela %s: %v", cfg.MySQL.Table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("erro ao ler as colunas da tabela %s: %v", cfg.MySQL.Table, err)
+	}
+	problems := models.CheckMapping(cfg.Mapping, columns)
+	if _, err := models.PrimaryKey(ctx, mysqlDB, cfg.MySQL.Database, cfg.MySQL.Table, cfg.MySQL.PrimaryKey); err != nil {
+		problems.Add("%v", err)
+	}
+	return problems.Err()
+}
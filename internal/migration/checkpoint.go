@@ -0,0 +1,143 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/models"
+	"MysqlToMongo/internal/snapshot"
+)
+
+// stateDir retorna o diretório dos arquivos de estado. Com general.job cada migração tem o seu,
+// para que migrações de tabelas diferentes possam rodar a partir do mesmo diretório
+func stateDir(job string) string {
+	if job == "" {
+		return "tmp"
+	}
+	return filepath.Join("tmp", "jobs", job)
+}
+
+// checkpointPath retorna o caminho do arquivo de checkpoint com a situação de cada chunk
+func checkpointPath(job string) string {
+	return filepath.Join(stateDir(job), "checkpoint.json")
+}
+
+// snapshotPositionPath retorna o caminho do arquivo com a posição do binlog do snapshot, usada pela carga incremental
+func snapshotPositionPath(job string) string {
+	return filepath.Join(stateDir(job), "snapshot_position.json")
+}
+
+// deadLetterPath retorna o arquivo padrão do dead-letter. O nome é fixo por job para que a retomada
+// continue gravando no mesmo arquivo
+func deadLetterPath(general config.GeneralConfig) string {
+	name := "dead_letter"
+	if general.Job != "" {
+		name += "_" + general.Job
+	}
+	dir := general.LogDir
+	if dir == "" {
+		dir = defaultLogDir
+	}
+	return filepath.Join(dir, name+".jsonl")
+}
+
+// Intervalo entre as gravações periódicas do checkpoint
+const checkpointInterval = 30 * time.Second
+
+// Checkpoint representa a situação dos chunks de uma migração, usada para retomá-la
+type Checkpoint struct {
+	UpdatedAt    time.Time          `json:"updated_at"`
+	Table        string             `json:"table"`
+	Collection   string             `json:"collection"`
+	Key          string             `json:"key"` // Coluna da chave que delimita os chunks
+	TotalRecords int64              `json:"total_records"`
+	ChunkSize    int64              `json:"chunk_size"`
+	Chunks       []models.Chunk     `json:"chunks"`
+	Snapshot     *snapshot.Position `json:"snapshot,omitempty"` // Posição do binlog do snapshot em que a migração começou
+	DeadLetters  int64              `json:"dead_letters"`       // Registros enviados ao dead-letter, somados entre as execuções
+}
+
+// newCheckpoint monta o checkpoint a partir da situação atual da fila
+func newCheckpoint(table, collection, key string, totalRecords, chunkSize int64, queue *models.ChunkQueue, position *snapshot.Position, deadLetters int64) Checkpoint {
+	return Checkpoint{
+		UpdatedAt:    time.Now(),
+		Table:        table,
+		Collection:   collection,
+		Key:          key,
+		TotalRecords: totalRecords,
+		ChunkSize:    chunkSize,
+		Chunks:       queue.Snapshot(),
+		Snapshot:     position,
+		DeadLetters:  deadLetters,
+	}
+}
+
+// matches verifica se o checkpoint pertence à mesma tabela, collection e divisão em chunks.
+// Os chunks são intervalos da chave, então continuam válidos se registros forem inseridos ou removidos;
+// checkpoints anteriores à leitura por chave não têm Key e não são retomados
+func (c Checkpoint) matches(table, collection, key string, chunkSize int64) bool {
+	return c.Table == table && c.Collection == collection &&
+		c.Key != "" && c.Key == key && c.ChunkSize == chunkSize
+}
+
+// resumeChunks retorna os chunks do checkpoint prontos para serem retomados.
+// Chunks em andamento ou com falha voltam a ficar pendentes a partir do último registro confirmado
+func (c Checkpoint) resumeChunks() []*models.Chunk {
+	chunks := make([]*models.Chunk, len(c.Chunks))
+	for i := range c.Chunks {
+		chunk := c.Chunks[i]
+		if chunk.Status != models.ChunkDone {
+			chunk.Status = models.ChunkPending
+			chunk.Attempts = 0
+		}
+		chunks[i] = &chunk
+	}
+	return chunks
+}
+
+// loadCheckpoint lê o checkpoint gravado por uma execução anterior
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("erro ao ler checkpoint: %v", err)
+	}
+	return &checkpoint, nil
+}
+
+// saveCheckpoint grava o checkpoint em disco
+func saveCheckpoint(path string, checkpoint Checkpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "    ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar checkpoint: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório do checkpoint: %v", err)
+	}
+
+	// Grava em arquivo temporário e renomeia para não deixar um checkpoint pela metade
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar checkpoint: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("erro ao gravar checkpoint: %v", err)
+	}
+	return nil
+}
+
+// removeCheckpoint apaga o checkpoint após uma migração concluída
+func removeCheckpoint(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao remover checkpoint: %v", err)
+	}
+	return nil
+}
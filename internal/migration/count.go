@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/models"
+	"MysqlToMongo/internal/rules"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CountReport representa o total de registros da origem e de documentos em cada collection de destino
+type CountReport struct {
+	SourceCount  int64            // -1 quando o MySQL não foi consultado
+	Collections  []string         // Collection principal seguida das collections de roteamento
+	TargetCounts map[string]int64 // Vazio quando o MongoDB não foi consultado
+}
+
+// Count conta os registros da tabela de origem e os documentos da collection principal e das collections
+// de roteamento. mysqlDB ou mongoClient nulos fazem o lado correspondente ser ignorado
+func Count(ctx context.Context, config *config.Config, mysqlDB *sql.DB, mongoClient *mongo.Client) (*CountReport, error) {
+	ruleEngine, err := rules.New(config.Rules, &models.OrderedDocument{})
+	if err != nil {
+		return nil, fmt.Errorf("erro nas regras de filtro: %v", err)
+	}
+
+	report := &CountReport{
+		SourceCount:  -1,
+		Collections:  append([]string{config.MongoDB.Collection}, ruleEngine.Collections()...),
+		TargetCounts: make(map[string]int64),
+	}
+	if mysqlDB != nil {
+		report.SourceCount, err = countSource(ctx, mysqlDB, config.MySQL.Table)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if mongoClient != nil {
+		report.TargetCounts, err = countCollections(ctx, mongoClient.Database(config.MongoDB.Database), report.Collections)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// countSource conta os registros da tabela de origem
+func countSource(ctx context.Context, mysqlDB *sql.DB, table string) (int64, error) {
+	var count int64
+	if err := mysqlDB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("erro ao contar registros: %v", err)
+	}
+	return count, nil
+}
+
+// countCollections conta os documentos de cada collection
+func countCollections(ctx context.Context, database *mongo.Database, names []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(names))
+	for _, name := range names {
+		count, err := database.Collection(name).CountDocuments(ctx, bson.D{})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao contar documentos da collection '%s': %v", name, err)
+		}
+		counts[name] = count
+	}
+	return counts, nil
+}
@@ -0,0 +1,169 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/models"
+	"MysqlToMongo/internal/retry"
+	"MysqlToMongo/internal/rules"
+	"MysqlToMongo/internal/snapshot"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Quantidade de documentos de exemplo mostrados no relatório do dry-run
+const dryRunSamples = 5
+
+// DryRun lê e converte todos os registros como na migração, sem acessar o MongoDB,
+// e mostra as estatísticas da conversão, a taxa de nulos por campo e documentos de exemplo
+func DryRun(ctx context.Context, config *config.Config, mysqlDB *sql.DB) error {
+	// Configura o logging
+	logFile, err := setupLogging(config.General)
+	if err != nil {
+		return fmt.Errorf("erro ao configurar logging: %v", err)
+	}
+	defer logFile.Close()
+	stopMetrics, err := startMetrics(config)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+
+	logging.Info("Dry-run: nenhuma alteração será feita no MongoDB")
+
+	ruleEngine, err := rules.New(config.Rules, &models.OrderedDocument{})
+	if err != nil {
+		return fmt.Errorf("erro nas regras de filtro: %v", err)
+	}
+	retryPolicy := retry.NewPolicy(config.General.Retry)
+
+	numWorkers := config.General.NumWorkers.Value
+	if config.General.NumWorkers.IsAuto() {
+		numWorkers = autoNumWorkers()
+	}
+
+	key, err := models.PrimaryKey(ctx, mysqlDB, config.MySQL.Database, config.MySQL.Table, config.MySQL.PrimaryKey)
+	if err != nil {
+		return err
+	}
+
+	// Com consistent_snapshot o dry-run lê os registros no mesmo ponto no tempo, como a migração
+	var snap *snapshot.Snapshot
+	var source models.Querier = mysqlDB
+	if config.General.ConsistentSnapshot {
+		readers := positiveOr(config.General.Pipeline.Readers, numWorkers)
+		snap, err = snapshot.Open(ctx, mysqlDB, config.MySQL.Table, readers)
+		if err != nil {
+			return err
+		}
+		defer snap.Close()
+		source = snap.Conns[0]
+		logging.Infof("Snapshot consistente aberto em %d conexões (%s)", readers, snap.Position)
+	}
+
+	chunks, err := splitChunks(ctx, config, retryPolicy, source, key, math.MinInt64)
+	if err != nil {
+		return err
+	}
+	totalRecords := totalRows(chunks)
+
+	chunkAttempts := config.General.ChunkAttempts
+	if chunkAttempts <= 0 {
+		chunkAttempts = defaultChunkAttempts
+	}
+	queue := models.NewChunkQueue(chunks, chunkAttempts)
+
+	throttler, err := newThrottler(config, mysqlDB)
+	if err != nil {
+		return err
+	}
+	defer throttler.Close()
+	throttleCtx, stopThrottle := context.WithCancel(ctx)
+	defer stopThrottle()
+	go throttler.Run(throttleCtx)
+
+	stats := models.NewDryRunStats(config.Mapping, dryRunSamples)
+	progressChan := make(chan int, numWorkers)
+	pipeline := &models.Pipeline{
+		Chunks:        queue,
+		MySQLDB:       mysqlDB,
+		SnapshotConns: snap.Connections(),
+		Key:           key,
+		Config:        config,
+		Rules:         ruleEngine,
+		Retry:         retryPolicy,
+		Throttle:      throttler,
+		BatchSizer:    models.NewBatchSizer(config.General.BatchSize.Value, calculateMemoryLimit()/2, numWorkers),
+		DryRun:        stats,
+		ProgressChan:  progressChan,
+	}
+	configurePipeline(pipeline, config.General.Pipeline, numWorkers)
+	pipeline.Start(ctx)
+	monitorDone := monitorProgress(config, pipeline, throttler, totalRecords, 0, progressChan)
+
+	err = pipeline.Wait()
+	stopThrottle()
+	close(progressChan)
+	<-monitorDone
+	if ctx.Err() != nil {
+		return ErrInterrupted
+	}
+	if err != nil {
+		return err
+	}
+
+	// Com o pipeline encerrado, todo registro lido já foi convertido ou descartado
+	logDryRunReport(stats, queue.Committed(), totalRecords)
+	logRulesSummary(ruleEngine)
+
+	if failed := queue.Count(models.ChunkFailed); failed > 0 {
+		return fmt.Errorf("%d chunks falharam na leitura após %d tentativas", failed, chunkAttempts)
+	}
+	return nil
+}
+
+// logDryRunReport mostra as estatísticas da conversão e os documentos de exemplo em Extended JSON.
+// read é o total de registros lidos e expected, o total estimado na divisão em chunks
+func logDryRunReport(stats *models.DryRunStats, read, expected int64) {
+	documents := stats.Documents()
+	errors, examples := stats.Errors()
+
+	logging.Info("")
+	logging.Info("Resultado do dry-run:")
+	logging.Infof("  Registros lidos: %d de %d previstos - convertidos: %d - com erro na conversão: %d", read, expected, documents, errors)
+	for _, example := range examples {
+		logging.Infof("    %s", example)
+	}
+
+	if documents > 0 {
+		logging.Info("")
+		logging.Info("Campos (nulos / falhas do conversor):")
+		for _, field := range stats.Fields() {
+			logging.Infof("  %-20s %-22s nulos: %6.2f%% - falhas: %d",
+				field.Field, field.Converter, float64(field.Nulls)/float64(documents)*100, field.Failures)
+			for _, example := range field.Examples {
+				logging.Infof("    valor não convertido: %s", example)
+			}
+		}
+	}
+
+	samples := stats.Samples()
+	if len(samples) == 0 {
+		return
+	}
+	logging.Info("")
+	logging.Info("Documentos de exemplo:")
+	for _, doc := range samples {
+		data, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			logging.Infof("  erro ao serializar documento: %v", err)
+			continue
+		}
+		logging.Infof("  %s", data)
+	}
+}
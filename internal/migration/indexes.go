@@ -1,46 +1,406 @@
 package migration
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"strings"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/logging"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Tipos de campo aceitos na declaração dos índices
+const (
+	IndexAsc    = "asc"
+	IndexDesc   = "desc"
+	IndexText   = "text"
+	IndexHashed = "hashed"
+)
+
+// Índices criados quando nenhum índice é declarado na configuração. O índice de cpf ignora
+// documentos sem CPF, com o mesmo filtro de duplicates.CPFPartialFilter
+var defaultIndexes = []config.IndexConfig{
+	{Keys: []config.IndexKeyConfig{{Field: "cpf"}}, Unique: true, PartialFilter: json.RawMessage(`{"cpf": {"$type": "string", "$gt": ""}}`)},
+	{Keys: []config.IndexKeyConfig{{Field: "nome"}}},
+	{Keys: []config.IndexKeyConfig{{Field: "contatos.emails"}}},
+	{Keys: []config.IndexKeyConfig{{Field: "contatos.telefones"}}},
+}
+
+// declaredIndex representa um índice declarado já convertido para o formato do driver
+type declaredIndex struct {
+	collection string
+	name       string
+	model      mongo.IndexModel
+	spec       config.IndexConfig
+}
+
+// IndexReconcileOptions representa o que fazer com índices sobrando ou diferentes na reconciliação
+type IndexReconcileOptions struct {
+	Check       bool // Apenas mostra as diferenças, sem alterar nada
+	DropExtras  bool // Remove índices existentes que não foram declarados
+	RecreateOld bool // Recria índices declarados cujas opções mudaram
+}
+
 // Função para criar índices
-func CreateIndexes(ctx context.Context, collection *mongo.Collection) error {
-	// Índice único para CPF
-	cpfIndex := mongo.IndexModel{
-		Keys: bson.D{{Key: "cpf", Value: 1}},
-		Options: options.Index().
-			SetUnique(true),
+func CreateIndexes(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+	indexes, err := declaredIndexes(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Cria os índices agrupados por collection
+	byCollection := make(map[string][]mongo.IndexModel)
+	var order []string
+	for _, index := range indexes {
+		if _, ok := byCollection[index.collection]; !ok {
+			order = append(order, index.collection)
+		}
+		byCollection[index.collection] = append(byCollection[index.collection], index.model)
+	}
+	for _, name := range order {
+		if _, err := db.Collection(name).Indexes().CreateMany(ctx, byCollection[name]); err != nil {
+			return fmt.Errorf("erro ao criar índices na collection '%s': %v", name, err)
+		}
+	}
+
+	logging.Info("Índices criados com sucesso!")
+	return nil
+}
+
+// ReconcileIndexes compara os índices existentes com os declarados: cria os que faltam
+// e mostra, remove ou recria os que sobram ou mudaram conforme as opções
+func ReconcileIndexes(ctx context.Context, db *mongo.Database, cfg *config.Config, opts IndexReconcileOptions) error {
+	indexes, err := declaredIndexes(cfg)
+	if err != nil {
+		return err
+	}
+
+	declared := make(map[string]map[string]declaredIndex)
+	var order []string
+	for _, index := range indexes {
+		if declared[index.collection] == nil {
+			declared[index.collection] = make(map[string]declaredIndex)
+			order = append(order, index.collection)
+		}
+		declared[index.collection][index.name] = index
 	}
 
-	// Índice para nome
-	nomeIndex := mongo.IndexModel{
-		Keys: bson.D{{Key: "nome", Value: 1}},
+	for _, name := range order {
+		collection := db.Collection(name)
+		existing, err := listIndexes(ctx, collection)
+		if err != nil {
+			return err
+		}
+		logging.Infof("Collection '%s':", name)
+
+		for _, index := range indexes {
+			if index.collection != name {
+				continue
+			}
+			current, ok := existing[index.name]
+			switch {
+			case !ok:
+				logging.Infof("  + %s: não existe", index.name)
+				if !opts.Check {
+					if _, err := collection.Indexes().CreateOne(ctx, index.model); err != nil {
+						return fmt.Errorf("erro ao criar índice '%s': %v", index.name, err)
+					}
+					logging.Infof("    criado")
+				}
+			case !sameIndex(index, current):
+				logging.Infof("  ~ %s: opções diferentes das declaradas (existente: %s)", index.name, current.String())
+				if !opts.Check && opts.RecreateOld {
+					if _, err := collection.Indexes().DropOne(ctx, index.name); err != nil {
+						return fmt.Errorf("erro ao remover índice '%s': %v", index.name, err)
+					}
+					if _, err := collection.Indexes().CreateOne(ctx, index.model); err != nil {
+						return fmt.Errorf("erro ao recriar índice '%s': %v", index.name, err)
+					}
+					logging.Infof("    recriado")
+				}
+			default:
+				logging.Infof("  = %s", index.name)
+			}
+		}
+
+		for indexName := range existing {
+			if indexName == "_id_" {
+				continue
+			}
+			if _, ok := declared[name][indexName]; ok {
+				continue
+			}
+			logging.Infof("  - %s: não declarado", indexName)
+			if !opts.Check && opts.DropExtras {
+				if _, err := collection.Indexes().DropOne(ctx, indexName); err != nil {
+					return fmt.Errorf("erro ao remover índice '%s': %v", indexName, err)
+				}
+				logging.Infof("    removido")
+			}
+		}
 	}
+	return nil
+}
 
-	// Índice para emails (dentro do array de contatos)
-	emailIndex := mongo.IndexModel{
-		Keys: bson.D{{Key: "contatos.emails", Value: 1}},
+// declaredIndexes converte os índices da configuração para o formato do driver
+func declaredIndexes(cfg *config.Config) ([]declaredIndex, error) {
+	specs := cfg.Indexes
+	if len(specs) == 0 {
+		specs = defaultIndexes
 	}
+	specs = append(specs, searchIndexes(cfg, specs)...)
 
-	// Índice para telefones (dentro do array de contatos)
-	telefoneIndex := mongo.IndexModel{
-		Keys: bson.D{{Key: "contatos.telefones", Value: 1}},
+	indexes := make([]declaredIndex, 0, len(specs))
+	seen := make(map[string]bool)
+	for i, spec := range specs {
+		index, err := buildIndex(spec, cfg.MongoDB.Collection)
+		if err != nil {
+			return nil, fmt.Errorf("índice %d: %v", i+1, err)
+		}
+		key := index.collection + "." + index.name
+		if seen[key] {
+			return nil, fmt.Errorf("índice '%s' declarado mais de uma vez na collection '%s'", index.name, index.collection)
+		}
+		seen[key] = true
+		indexes = append(indexes, index)
 	}
+	return indexes, nil
+}
 
-	// Criar todos os índices
-	indexes := []mongo.IndexModel{cpfIndex, nomeIndex, emailIndex, telefoneIndex}
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
+// duplicatesIndex retorna o índice único de CPF criado antes da carga quando duplicates.strategy está ativo.
+// Um índice de cpf declarado é usado com as mesmas opções, para que a criação dos índices após a carga
+// não encontre um índice de mesma chave com opções diferentes
+func duplicatesIndex(cfg *config.Config) (mongo.IndexModel, error) {
+	indexes, err := declaredIndexes(cfg)
 	if err != nil {
-		return fmt.Errorf("erro ao criar índices: %v", err)
+		return mongo.IndexModel{}, err
+	}
+	for _, index := range indexes {
+		if index.collection != cfg.MongoDB.Collection || len(index.spec.Keys) != 1 || index.spec.Keys[0].Field != "cpf" {
+			continue
+		}
+		if !index.spec.Unique {
+			return mongo.IndexModel{}, fmt.Errorf("o índice '%s' de cpf precisa ser único para detectar os CPFs duplicados", index.name)
+		}
+		return index.model, nil
 	}
+	return duplicates.UniqueIndex(), nil
+}
 
-	log.Println("Índices criados com sucesso!")
-	return nil
+// searchIndexes retorna os índices dos campos de busca por nome que ainda não foram declarados
+func searchIndexes(cfg *config.Config, declared []config.IndexConfig) []config.IndexConfig {
+	var fields []string
+	if cfg.Search.NormalizedName {
+		fields = append(fields, "nome_busca")
+	}
+	if cfg.Search.PhoneticName {
+		fields = append(fields, "nome_fonetico")
+	}
+
+	var indexes []config.IndexConfig
+	for _, field := range fields {
+		found := false
+		for _, spec := range declared {
+			if len(spec.Keys) > 0 && spec.Keys[0].Field == field && (spec.Collection == "" || spec.Collection == cfg.MongoDB.Collection) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			indexes = append(indexes, config.IndexConfig{Keys: []config.IndexKeyConfig{{Field: field}}})
+		}
+	}
+	return indexes
+}
+
+// buildIndex monta o modelo do índice a partir da declaração
+func buildIndex(spec config.IndexConfig, mainCollection string) (declaredIndex, error) {
+	if len(spec.Keys) == 0 {
+		return declaredIndex{}, fmt.Errorf("nenhum campo informado em 'keys'")
+	}
+
+	keys := bson.D{}
+	nameParts := make([]string, 0, len(spec.Keys))
+	for _, key := range spec.Keys {
+		if key.Field == "" {
+			return declaredIndex{}, fmt.Errorf("campo sem nome em 'keys'")
+		}
+		var value interface{}
+		switch key.Type {
+		case "", IndexAsc:
+			value = 1
+		case IndexDesc:
+			value = -1
+		case IndexText:
+			value = IndexText
+		case IndexHashed:
+			value = IndexHashed
+		default:
+			return declaredIndex{}, fmt.Errorf("tipo '%s' inválido para o campo '%s': use asc, desc, text ou hashed", key.Type, key.Field)
+		}
+		keys = append(keys, bson.E{Key: key.Field, Value: value})
+		nameParts = append(nameParts, fmt.Sprintf("%s_%v", key.Field, value))
+	}
+
+	name := spec.Name
+	if name == "" {
+		// Mesmo nome que o MongoDB geraria
+		name = strings.Join(nameParts, "_")
+	}
+	collection := spec.Collection
+	if collection == "" {
+		collection = mainCollection
+	}
+
+	opts := options.Index().SetName(name)
+	if spec.Unique {
+		opts.SetUnique(true)
+	}
+	if spec.Sparse {
+		opts.SetSparse(true)
+	}
+	if len(spec.PartialFilter) > 0 {
+		var filter bson.D
+		if err := bson.UnmarshalExtJSON(spec.PartialFilter, false, &filter); err != nil {
+			return declaredIndex{}, fmt.Errorf("partial_filter inválido: %v", err)
+		}
+		opts.SetPartialFilterExpression(filter)
+	}
+	if spec.Collation != nil {
+		opts.SetCollation(&options.Collation{
+			Locale:    spec.Collation.Locale,
+			Strength:  spec.Collation.Strength,
+			CaseLevel: spec.Collation.CaseLevel,
+		})
+	}
+	if spec.ExpireAfterSeconds != nil {
+		opts.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+	}
+	if spec.DefaultLanguage != "" {
+		opts.SetDefaultLanguage(spec.DefaultLanguage)
+	}
+
+	return declaredIndex{
+		collection: collection,
+		name:       name,
+		model:      mongo.IndexModel{Keys: keys, Options: opts},
+		spec:       spec,
+	}, nil
+}
+
+// listIndexes retorna os índices existentes na collection pelo nome
+func listIndexes(ctx context.Context, collection *mongo.Collection) (map[string]bson.Raw, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar índices da collection '%s': %v", collection.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	existing := make(map[string]bson.Raw)
+	for cursor.Next(ctx) {
+		name, _ := cursor.Current.Lookup("name").StringValueOK()
+		existing[name] = append(bson.Raw(nil), cursor.Current...)
+	}
+	return existing, cursor.Err()
+}
+
+// sameIndex compara as opções declaradas com as de um índice existente
+func sameIndex(index declaredIndex, current bson.Raw) bool {
+	spec := index.spec
+
+	// Índices de texto são listados com as chaves internas _fts e _ftsx
+	if !hasTextKey(spec) {
+		expected, err := bson.Marshal(index.model.Keys)
+		if err != nil || !sameKeys(expected, current.Lookup("key").Document()) {
+			return false
+		}
+	}
+
+	if boolOption(current, "unique") != spec.Unique || boolOption(current, "sparse") != spec.Sparse {
+		return false
+	}
+
+	ttl, hasTTL := current.Lookup("expireAfterSeconds").AsInt64OK()
+	if (spec.ExpireAfterSeconds != nil) != hasTTL || (hasTTL && ttl != int64(*spec.ExpireAfterSeconds)) {
+		return false
+	}
+
+	locale, _ := current.Lookup("collation", "locale").StringValueOK()
+	if spec.Collation == nil {
+		if locale != "" && locale != "simple" {
+			return false
+		}
+	} else {
+		strength, _ := current.Lookup("collation", "strength").AsInt64OK()
+		if locale != spec.Collation.Locale || (spec.Collation.Strength != 0 && strength != int64(spec.Collation.Strength)) {
+			return false
+		}
+	}
+
+	partial, hasPartial := current.Lookup("partialFilterExpression").DocumentOK()
+	if (len(spec.PartialFilter) > 0) != hasPartial {
+		return false
+	}
+	if hasPartial {
+		var filter bson.D
+		if err := bson.UnmarshalExtJSON(spec.PartialFilter, false, &filter); err != nil {
+			return false
+		}
+		expected, err := bson.Marshal(filter)
+		if err != nil || !bytes.Equal(expected, partial) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKeys compara as chaves do índice sem depender do tipo numérico usado na direção
+func sameKeys(expected, current bson.Raw) bool {
+	expectedElems, err := expected.Elements()
+	if err != nil {
+		return false
+	}
+	currentElems, err := current.Elements()
+	if err != nil || len(expectedElems) != len(currentElems) {
+		return false
+	}
+	for i := range expectedElems {
+		if expectedElems[i].Key() != currentElems[i].Key() {
+			return false
+		}
+		expectedValue, currentValue := expectedElems[i].Value(), currentElems[i].Value()
+		if direction, ok := expectedValue.AsInt64OK(); ok {
+			if current, ok := currentValue.AsInt64OK(); !ok || current != direction {
+				return false
+			}
+			continue
+		}
+		if expectedValue.StringValue() != currentValue.StringValue() {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTextKey verifica se o índice declarado é de texto
+func hasTextKey(spec config.IndexConfig) bool {
+	for _, key := range spec.Keys {
+		if key.Type == IndexText {
+			return true
+		}
+	}
+	return false
+}
+
+// boolOption lê uma opção booleana do índice existente, considerando ausente como false
+func boolOption(index bson.Raw, name string) bool {
+	value, ok := index.Lookup(name).BooleanOK()
+	return ok && value
 }
@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/duplicates"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestDuplicatesIndex(t *testing.T) {
+	cpf := []config.IndexKeyConfig{{Field: "cpf"}}
+	tests := []struct {
+		name        string
+		indexes     []config.IndexConfig
+		wantName    string
+		wantPartial bool
+		wantErr     string
+	}{
+		{"índices padrão", nil, "cpf_1", true, ""},
+		{"sem índice de cpf declarado", []config.IndexConfig{{Keys: []config.IndexKeyConfig{{Field: "nome"}}}}, "", true, ""},
+		{"índice de cpf declarado", []config.IndexConfig{{Name: "cpf_unico", Keys: cpf, Unique: true}}, "cpf_unico", false, ""},
+		{"índice de cpf não único", []config.IndexConfig{{Keys: cpf}}, "", false, "precisa ser único"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Indexes: tt.indexes}
+			cfg.MongoDB.Collection = "pessoas"
+			index, err := duplicatesIndex(cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("duplicatesIndex() erro = %v, esperado %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name := indexName(index); tt.wantName != "" && name != tt.wantName {
+				t.Errorf("nome = %q, esperado %q", name, tt.wantName)
+			}
+			partial := index.Options.PartialFilterExpression
+			if tt.wantPartial && !samePartialFilter(t, partial, duplicates.CPFPartialFilter) {
+				t.Errorf("partialFilterExpression = %v, esperado %v", partial, duplicates.CPFPartialFilter)
+			}
+			if !tt.wantPartial && partial != nil {
+				t.Errorf("partialFilterExpression = %v, esperado nenhum", partial)
+			}
+		})
+	}
+}
+
+// indexName retorna o nome definido nas opções do índice
+func indexName(index mongo.IndexModel) string {
+	if index.Options == nil || index.Options.Name == nil {
+		return ""
+	}
+	return *index.Options.Name
+}
+
+// samePartialFilter compara os filtros pela serialização BSON
+func samePartialFilter(t *testing.T, got interface{}, want bson.D) bool {
+	t.Helper()
+	if got == nil {
+		return false
+	}
+	a, err := bson.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bson.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(a, b)
+}
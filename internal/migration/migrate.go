@@ -3,112 +3,381 @@ package migration
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"math"
 	"os"
 	"path/filepath"
-	"sync"
+	"runtime"
 	"time"
 
 	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/database"
+	"MysqlToMongo/internal/deadletter"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/metrics"
 	"MysqlToMongo/internal/models"
+	"MysqlToMongo/internal/retry"
+	"MysqlToMongo/internal/rules"
+	"MysqlToMongo/internal/snapshot"
+	"MysqlToMongo/internal/throttle"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Diretório padrão dos logs, dead-letter e relatórios
+const defaultLogDir = "tmp/logs"
+
+// logPath monta o caminho de um arquivo no diretório de logs com o nome da migração, se houver, o horário
+// e o identificador da execução, para que execuções iniciadas no mesmo minuto não compartilhem arquivos
+func logPath(general config.GeneralConfig, prefix, ext string) string {
+	name := prefix
+	if general.Job != "" {
+		name += "_" + general.Job
+	}
+	dir := general.LogDir
+	if dir == "" {
+		dir = defaultLogDir
+	}
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	return filepath.Join(dir, fmt.Sprintf("%s_%s_%s.%s", name, timestamp, logging.RunID(), ext))
+}
+
 // setupLogging configura o log para arquivo e console
-func setupLogging() (*os.File, error) {
+func setupLogging(general config.GeneralConfig) (*os.File, error) {
+	// Gera o nome do arquivo com horário e identificador da execução
+	logFile := logPath(general, "export", "log")
+
 	// Cria o diretório de logs se não existir
-	if err := os.MkdirAll("tmp/logs", 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
 		return nil, fmt.Errorf("erro ao criar diretório de logs: %v", err)
 	}
 
-	// Gera o nome do arquivo com timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04")
-	logFile := filepath.Join("tmp", "logs", fmt.Sprintf("export_%s.log", timestamp))
-
 	// Abre o arquivo de log
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao abrir arquivo de log: %v", err)
 	}
 
-	// Configura o log para escrever tanto no arquivo quanto no console
-	log.SetOutput(io.MultiWriter(os.Stdout, file))
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+	// Configura o log para escrever tanto no arquivo quanto no console, identificando a execução no formato JSON
+	logging.SetOutput(io.MultiWriter(os.Stdout, file))
+	attrs := []any{"run_id", logging.RunID()}
+	if general.Job != "" {
+		attrs = append(attrs, "job", general.Job)
+	}
+	logging.SetAttrs(attrs...)
+	logging.Infof("Log da execução %s gravado em %s", logging.RunID(), logFile)
 
 	return file, nil
 }
 
-// MigrateData executa o processo de migração dos dados
-func MigrateData(config *config.Config, mysqlDB *sql.DB, mongoClient *mongo.Client) error {
+// ErrInterrupted indica que a migração foi interrompida por sinal antes de terminar
+var ErrInterrupted = errors.New("migração interrompida")
+
+// MigrateData executa o processo de migração dos dados.
+// Se o contexto for cancelado, os lotes em andamento são gravados, o checkpoint é salvo e ErrInterrupted é retornado
+func MigrateData(ctx context.Context, config *config.Config, mysqlDB *sql.DB, mongoClient *mongo.Client) error {
 	// Configura o logging
-	logFile, err := setupLogging()
+	logFile, err := setupLogging(config.General)
 	if err != nil {
 		return fmt.Errorf("erro ao configurar logging: %v", err)
 	}
 	defer logFile.Close()
+	if config.General.Job != "" {
+		logging.Infof("Migração '%s'", config.General.Job)
+	}
+	stopMetrics, err := startMetrics(config)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+	checkpointFile := checkpointPath(config.General.Job)
 
-	ctx := context.Background()
 	collection := mongoClient.Database(config.MongoDB.Database).Collection(config.MongoDB.Collection)
 
-	// Limpa a collection antes de começar
-	log.Printf("Limpando collection '%s' existente...", config.MongoDB.Collection)
-	if err := collection.Drop(ctx); err != nil {
-		return fmt.Errorf("erro ao limpar collection: %v", err)
+	// Prepara as regras de filtro e roteamento
+	ruleEngine, err := rules.New(config.Rules, &models.OrderedDocument{})
+	if err != nil {
+		return fmt.Errorf("erro nas regras de filtro: %v", err)
+	}
+
+	// Valida os índices declarados antes da carga, para não descobrir o erro só no final
+	if _, err := declaredIndexes(config); err != nil {
+		return fmt.Errorf("erro na declaração dos índices: %v", err)
 	}
-	log.Printf("Collection '%s' limpa com sucesso!", config.MongoDB.Collection)
-	log.Println("")
 
-	// Obtém o total de registros
-	var totalRecords int64
-	err = mysqlDB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", config.MySQL.Table)).Scan(&totalRecords)
+	// Prepara o tratamento de CPFs duplicados
+	resolver, err := duplicates.New(config.Duplicates, mongoClient.Database(config.MongoDB.Database), config.MongoDB.Collection)
 	if err != nil {
-		return fmt.Errorf("erro ao contar registros: %v", err)
+		return fmt.Errorf("erro na configuração de duplicados: %v", err)
 	}
 
+	// Política de novas tentativas para falhas transitórias
+	retryPolicy := retry.NewPolicy(config.General.Retry)
+
 	// Define o número de workers
-	numWorkers := 5
-	chunks := SplitWork(totalRecords, numWorkers)
+	numWorkers := config.General.NumWorkers.Value
+	if config.General.NumWorkers.IsAuto() {
+		numWorkers = autoNumWorkers()
+	}
 
-	// Canais para controle
-	errorChan := make(chan error, numWorkers)
-	progressChan := make(chan int, numWorkers)
-	var wg sync.WaitGroup
-
-	// Inicia os workers
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		worker := &models.MigrationWorker{
-			ID:           i + 1,
-			StartID:      chunks[i].Start,
-			EndID:        chunks[i].End,
-			MySQLDB:      mysqlDB,
-			MongoClient:  mongoClient,
-			Config:       config,
-			Wg:           &wg,
-			ErrorChan:    errorChan,
-			ProgressChan: progressChan,
-		}
-
-		go func(w *models.MigrationWorker) {
-			defer w.Wg.Done()
-			if err := w.ProcessBatch(ctx); err != nil {
-				w.ErrorChan <- fmt.Errorf("erro no processador %d: %v", w.ID, err)
+	// Coluna usada para ler a tabela em ordem e derivar o _id dos documentos
+	key, err := models.PrimaryKey(ctx, mysqlDB, config.MySQL.Database, config.MySQL.Table, config.MySQL.PrimaryKey)
+	if err != nil {
+		return err
+	}
+
+	// Abre o snapshot antes da divisão em chunks, para que o total e a leitura vejam os mesmos registros
+	var snap *snapshot.Snapshot
+	var position *snapshot.Position
+	var source models.Querier = mysqlDB
+	if config.General.ConsistentSnapshot {
+		readers := positiveOr(config.General.Pipeline.Readers, numWorkers)
+		snap, err = snapshot.Open(ctx, mysqlDB, config.MySQL.Table, readers)
+		if err != nil {
+			return err
+		}
+		defer snap.Close()
+		position = &snap.Position
+		source = snap.Conns[0]
+		logging.Infof("Snapshot consistente aberto em %d conexões (%s)", readers, snap.Position)
+	}
+
+	// Divide o trabalho em chunks pela chave ou retoma os chunks de uma execução anterior
+	chunkSize := chunkSizeOf(config)
+	chunks, resumed, err := resumeOrSplit(ctx, config, retryPolicy, source, key)
+	if err != nil {
+		return err
+	}
+	totalRecords := totalRows(chunks)
+	resuming := resumed != nil
+	if resuming && position != nil {
+		// Os registros já gravados vieram de outro snapshot: a carga incremental precisa começar na posição mais antiga
+		logging.Warnf("a retomada lê de um novo snapshot; os registros já gravados podem estar desatualizados até a carga incremental")
+		if resumed.Snapshot != nil {
+			position = resumed.Snapshot
+			logging.Infof("Mantendo a posição do snapshot original (%s)", position)
+		}
+	}
+
+	if !resuming {
+		// Limpa a collection antes de começar
+		logging.Infof("Limpando collection '%s' existente...", config.MongoDB.Collection)
+		if err := collection.Drop(ctx); err != nil {
+			return fmt.Errorf("erro ao limpar collection: %v", err)
+		}
+		logging.Infof("Collection '%s' limpa com sucesso!", config.MongoDB.Collection)
+
+		// Limpa as collections de destino das regras de roteamento
+		for _, name := range ruleEngine.Collections() {
+			if err := mongoClient.Database(config.MongoDB.Database).Collection(name).Drop(ctx); err != nil {
+				return fmt.Errorf("erro ao limpar collection '%s': %v", name, err)
+			}
+			logging.Infof("Collection de roteamento '%s' limpa com sucesso!", name)
+		}
+
+		// Limpa a collection de CPFs duplicados descartados
+		if archive := resolver.ArchiveCollection(); archive != "" {
+			if err := mongoClient.Database(config.MongoDB.Database).Collection(archive).Drop(ctx); err != nil {
+				return fmt.Errorf("erro ao limpar collection '%s': %v", archive, err)
 			}
-		}(worker)
+		}
+	}
+
+	// O validador é aplicado antes da carga, para que os documentos inseridos já sejam validados
+	if config.Schema.Enabled {
+		if err := ApplyValidator(ctx, mongoClient.Database(config.MongoDB.Database), config); err != nil {
+			return err
+		}
+	}
+
+	if resolver != nil {
+		// O índice único precisa existir antes da carga para que os duplicados sejam detectados na inserção
+		index, err := duplicatesIndex(config)
+		if err != nil {
+			return err
+		}
+		if err := duplicates.EnsureUniqueIndex(ctx, collection, index); err != nil {
+			return err
+		}
+		logging.Infof("Tratamento de CPFs duplicados ativo (estratégia '%s')", resolver.Strategy())
+	}
+
+	// Prepara o destino dos registros com falha
+	deadLetter, err := openDeadLetter(ctx, config, mongoClient, !resuming)
+	if err != nil {
+		return err
+	}
+	defer deadLetter.Close()
+	if resuming {
+		deadLetter.Resume(resumed.DeadLetters)
+	}
+	logging.Infof("Registros com falha serão gravados em %s", deadLetter.Destination())
+	logging.Info("")
+
+	// Define o tamanho dos lotes
+	memoryLimit := calculateMemoryLimit()
+	batchSizer := models.NewBatchSizer(config.General.BatchSize.Value, memoryLimit/2, numWorkers)
+	if batchSizer.IsAuto() {
+		logging.Infof("Usando %d workers e lotes automáticos (inicial de %d documentos, memória disponível: %d MB)",
+			numWorkers, batchSizer.Size(), memoryLimit/(1<<20))
+	} else {
+		logging.Infof("Usando %d workers e lotes de %d documentos", numWorkers, batchSizer.Size())
+	}
+
+	// Fila de chunks consumida pelos workers sob demanda
+	chunkAttempts := config.General.ChunkAttempts
+	if chunkAttempts <= 0 {
+		chunkAttempts = defaultChunkAttempts
+	}
+	queue := models.NewChunkQueue(chunks, chunkAttempts)
+	logging.Infof("Trabalho dividido em %d chunks de até %d registros", len(chunks), chunkSize)
+
+	// Grava o checkpoint periodicamente para permitir retomar a migração após uma queda
+	stopCheckpoints := make(chan struct{})
+	checkpointsDone := make(chan struct{})
+	go func() {
+		defer close(checkpointsDone)
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCheckpoints:
+				return
+			case <-ticker.C:
+				if err := saveCheckpoint(checkpointFile, newCheckpoint(config.MySQL.Table, config.MongoDB.Collection, key, totalRecords, chunkSize, queue, position, deadLetter.Count())); err != nil {
+					logging.Warnf("%v", err)
+				}
+			}
+		}
+	}()
+
+	// Limites de velocidade para proteger o MySQL de origem
+	throttler, err := newThrottler(config, mysqlDB)
+	if err != nil {
+		return err
+	}
+	defer throttler.Close()
+	throttleCtx, stopThrottle := context.WithCancel(ctx)
+	defer stopThrottle()
+	go throttler.Run(throttleCtx)
+
+	// Pipeline de leitura, conversão e gravação
+	progressChan := make(chan int, numWorkers)
+	pipeline := &models.Pipeline{
+		Chunks:        queue,
+		MySQLDB:       mysqlDB,
+		SnapshotConns: snap.Connections(),
+		Key:           key,
+		MongoClient:   mongoClient,
+		Config:        config,
+		Rules:         ruleEngine,
+		Duplicates:    resolver,
+		DeadLetter:    deadLetter,
+		Retry:         retryPolicy,
+		Throttle:      throttler,
+		BatchSizer:    batchSizer,
+		ProgressChan:  progressChan,
+	}
+	configurePipeline(pipeline, config.General.Pipeline, numWorkers)
+	logging.Infof("Pipeline: %d leitores, %d conversores e %d gravadores (filas de %d linhas e %d documentos)",
+		pipeline.Readers, pipeline.Converters, pipeline.Writers, pipeline.RowBuffer, pipeline.DocumentBuffer)
+	pipeline.Start(ctx)
+
+	// Monitora o progresso, partindo dos registros já gravados em uma execução anterior
+	monitorDone := monitorProgress(config, pipeline, throttler, totalRecords, int(queue.Committed()), progressChan)
+
+	// Aguarda a conclusão
+	pipelineErr := pipeline.Wait()
+	stopThrottle()
+	close(progressChan)
+	<-monitorDone
+	close(stopCheckpoints)
+	<-checkpointsDone
+	checkpoint := newCheckpoint(config.MySQL.Table, config.MongoDB.Collection, key, totalRecords, chunkSize, queue, position, deadLetter.Count())
+
+	// Interrupção por sinal: salva até onde cada chunk chegou
+	if ctx.Err() != nil {
+		return interrupted(checkpointFile, checkpoint)
+	}
+
+	// Verifica erros, salvando até onde cada chunk chegou
+	if pipelineErr != nil {
+		if err := saveCheckpoint(checkpointFile, checkpoint); err != nil {
+			logging.Warnf("%v", err)
+		}
+		return pipelineErr
+	}
+
+	// Chunks que esgotaram as tentativas ficam no checkpoint para serem retomados
+	if failed := queue.Count(models.ChunkFailed); failed > 0 {
+		if err := saveCheckpoint(checkpointFile, checkpoint); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d chunks falharam após %d tentativas; corrija o problema e execute novamente com \"resume\" (checkpoint em %s)",
+			failed, chunkAttempts, checkpointFile)
+	}
+
+	// Aguarda um momento para garantir que todas as operações foram concluídas
+	time.Sleep(1 * time.Second)
+
+	if batchSizer.IsAuto() {
+		logging.Infof("Tamanho médio dos documentos: %d bytes - lote final: %d documentos",
+			batchSizer.AverageDocumentSize(), batchSizer.Size())
+	}
+
+	// Mostra o resumo das regras de filtro e roteamento
+	logRulesSummary(ruleEngine)
+	logDuplicatesSummary(resolver)
+
+	// Criar índices após a importação estar 100% completa
+	logging.Info("")
+	logging.Info("Criando índices...")
+	err = retryPolicy.Do(ctx, "criação de índices", func() error {
+		return CreateIndexes(ctx, mongoClient.Database(config.MongoDB.Database), config)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao criar índices: %v", err)
+	}
+
+	// Verifica se a taxa de falhas ficou dentro do limite configurado
+	if err := checkFailureRate(config, deadLetter, queue.Committed()); err != nil {
+		return err
+	}
+
+	// Registra a posição do snapshot para a carga incremental seguinte
+	if position != nil {
+		positionFile := snapshotPositionPath(config.General.Job)
+		if err := snapshot.SavePosition(positionFile, *position); err != nil {
+			return err
+		}
+		logging.Infof("Posição do snapshot gravada em %s: a carga incremental deve começar em %s", positionFile, position)
 	}
 
-	// Monitora o progresso
+	// Migração concluída: o checkpoint não é mais necessário
+	return removeCheckpoint(checkpointFile)
+}
+
+// monitorProgress mostra o progresso da migração a cada report_threshold registros.
+// O canal retornado é fechado depois que progressChan for fechado e o resumo final mostrado
+func monitorProgress(config *config.Config, pipeline *models.Pipeline, throttler *throttle.Throttler, totalRecords int64, processed int, progressChan <-chan int) <-chan struct{} {
+	monitorDone := make(chan struct{})
 	go func() {
-		totalProcessed := 0
+		defer close(monitorDone)
+		totalProcessed := processed
 		startTime := time.Now()
 		reportThreshold := config.General.ReportThreshold
 		isFirstReport := true
 
+		metrics.RecordsTotal.Set(float64(totalRecords))
+		metrics.QueueCapacity.WithLabelValues("rows").Set(float64(pipeline.RowBuffer))
+		metrics.QueueCapacity.WithLabelValues("documents").Set(float64(pipeline.DocumentBuffer))
+
 		for progress := range progressChan {
 			totalProcessed += progress
+			updateProgressMetrics(pipeline, totalRecords, totalProcessed-processed, totalProcessed, startTime)
 
 			// Always show first report and when we reach the threshold
 			if isFirstReport || totalProcessed >= reportThreshold {
@@ -117,12 +386,17 @@ func MigrateData(config *config.Config, mysqlDB *sql.DB, mongoClient *mongo.Clie
 				estimatedTotalTime := time.Duration(float64(totalRecords)/recordsPerSecond) * time.Second
 				remainingTime := estimatedTotalTime - elapsed
 
-				log.Printf("Progresso: %d/%d registros (%.2f%%) - Tempo decorrido: %v - Velocidade: %.2f registros/seg - Tempo restante estimado: %v",
+				rows, documents := pipeline.QueueDepths()
+				logging.Infof("Progresso: %d/%d registros (%.2f%%) - Tempo decorrido: %v - Velocidade: %.2f registros/seg - Tempo restante estimado: %v - Filas: linhas %d/%d, documentos %d/%d",
 					totalProcessed, totalRecords,
 					float64(totalProcessed)/float64(totalRecords)*100,
 					elapsed.Round(time.Second),
 					recordsPerSecond,
-					remainingTime.Round(time.Second))
+					remainingTime.Round(time.Second),
+					rows, pipeline.RowBuffer, documents, pipeline.DocumentBuffer)
+				if status := throttler.Status(); status != "" {
+					logging.Infof("Throttling: %s", status)
+				}
 
 				// Update the threshold for the next report
 				reportThreshold = (totalProcessed/config.General.ReportThreshold + 1) * config.General.ReportThreshold
@@ -131,35 +405,276 @@ func MigrateData(config *config.Config, mysqlDB *sql.DB, mongoClient *mongo.Clie
 		}
 
 		// Show final progress after all processing is done
+		updateProgressMetrics(pipeline, totalRecords, totalProcessed-processed, totalProcessed, startTime)
 		elapsed := time.Since(startTime)
 		recordsPerSecond := float64(totalProcessed) / elapsed.Seconds()
-		log.Printf("Progresso: %d/%d registros (100.00%%) - Tempo total: %v - Velocidade média: %.2f registros/seg",
+		logging.Infof("Progresso: %d/%d registros (%.2f%%) - Tempo total: %v - Velocidade média: %.2f registros/seg",
 			totalProcessed, totalRecords,
+			float64(totalProcessed)/float64(totalRecords)*100,
 			elapsed.Round(time.Second),
 			recordsPerSecond)
 	}()
+	return monitorDone
+}
 
-	// Aguarda a conclusão
-	wg.Wait()
-	close(errorChan)
-	close(progressChan)
+// updateProgressMetrics atualiza as métricas de progresso e das filas. O tempo restante é estimado
+// pela velocidade desta execução, sem contar os registros gravados antes de uma retomada
+func updateProgressMetrics(pipeline *models.Pipeline, totalRecords int64, processedNow, totalProcessed int, startTime time.Time) {
+	metrics.RecordsProcessed.Set(float64(totalProcessed))
+	rows, documents := pipeline.QueueDepths()
+	metrics.QueueDepth.WithLabelValues("rows").Set(float64(rows))
+	metrics.QueueDepth.WithLabelValues("documents").Set(float64(documents))
+
+	elapsed := time.Since(startTime).Seconds()
+	if processedNow <= 0 || elapsed <= 0 {
+		return
+	}
+	remaining := float64(totalRecords-int64(totalProcessed)) / (float64(processedNow) / elapsed)
+	metrics.EstimatedRemaining.Set(math.Max(remaining, 0))
+}
+
+// startMetrics expõe o endpoint /metrics quando configurado. A função retornada o encerra
+func startMetrics(config *config.Config) (func(), error) {
+	if !config.Metrics.Enabled {
+		return func() {}, nil
+	}
+	stop, err := metrics.Serve(config.Metrics.Addr)
+	if err != nil {
+		return nil, err
+	}
+	addr := config.Metrics.Addr
+	if addr == "" {
+		addr = metrics.DefaultAddr
+	}
+	logging.Infof("Métricas do Prometheus em http://%s/metrics", addr)
+	return stop, nil
+}
 
-	// Verifica erros
-	for err := range errorChan {
+// newThrottler cria os limites de velocidade da leitura, abrindo a conexão com a réplica
+// usada para medir o atraso de replicação quando configurada
+func newThrottler(config *config.Config, mysqlDB *sql.DB) (*throttle.Throttler, error) {
+	var replica *sql.DB
+	if adaptive := config.Throttle.Adaptive; adaptive.Enabled && adaptive.Replica.Host != "" {
+		db, err := database.OpenMySQL(adaptive.Replica)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("erro ao conectar à réplica do throttling: %v", err)
 		}
+		replica = db
 	}
 
-	// Aguarda um momento para garantir que todas as operações foram concluídas
-	time.Sleep(1 * time.Second)
+	throttler, err := throttle.New(config.Throttle, mysqlDB, replica)
+	if err != nil {
+		if replica != nil {
+			replica.Close()
+		}
+		return nil, fmt.Errorf("erro na configuração de throttling: %v", err)
+	}
+	if throttler != nil {
+		logging.Infof("Throttling ativo: %s", throttler.Status())
+	}
+	return throttler, nil
+}
 
-	// Criar índices após a importação estar 100% completa
-	log.Println("")
-	log.Println("Criando índices...")
-	if err := CreateIndexes(ctx, collection); err != nil {
-		return fmt.Errorf("erro ao criar índices: %v", err)
+// configurePipeline define o número de goroutines de cada estágio e a capacidade das filas,
+// usando num_workers para leitores e gravadores e o número de CPUs para os conversores.
+// No modo snapshot há exatamente um leitor por conexão do snapshot
+func configurePipeline(pipeline *models.Pipeline, cfg config.PipelineConfig, numWorkers int) {
+	pipeline.Readers = positiveOr(cfg.Readers, numWorkers)
+	if len(pipeline.SnapshotConns) > 0 {
+		pipeline.Readers = len(pipeline.SnapshotConns)
+	}
+	pipeline.Converters = positiveOr(cfg.Converters, runtime.NumCPU())
+	pipeline.Writers = positiveOr(cfg.Writers, numWorkers)
+	pipeline.RowBuffer = positiveOr(cfg.RowBuffer, defaultQueueBuffer)
+	pipeline.DocumentBuffer = positiveOr(cfg.DocumentBuffer, defaultQueueBuffer)
+}
+
+// positiveOr retorna value se for positivo ou fallback caso contrário
+func positiveOr(value, fallback int) int {
+	if value > 0 {
+		return value
 	}
+	return fallback
+}
 
+// resumeOrSplit retoma os chunks do checkpoint quando "resume" está ativo e o checkpoint é compatível,
+// retornando também o checkpoint; caso contrário divide a tabela em novos chunks.
+// Na retomada, os registros com chave posterior ao último chunk do checkpoint formam novos chunks
+func resumeOrSplit(ctx context.Context, config *config.Config, policy retry.Policy, source models.Querier, key string) ([]*models.Chunk, *Checkpoint, error) {
+	if config.General.Resume {
+		path := checkpointPath(config.General.Job)
+		checkpoint, err := loadCheckpoint(path)
+		switch {
+		case err != nil:
+			logging.Infof("Nenhum checkpoint para retomar (%v), iniciando do zero", err)
+		case !checkpoint.matches(config.MySQL.Table, config.MongoDB.Collection, key, chunkSizeOf(config)):
+			logging.Infof("Checkpoint em %s não corresponde à tabela, collection, chave ou chunk_size atuais, iniciando do zero", path)
+		default:
+			chunks := checkpoint.resumeChunks()
+			from := int64(math.MinInt64)
+			if len(chunks) > 0 {
+				from = chunks[len(chunks)-1].End + 1
+			}
+			added, err := splitChunks(ctx, config, policy, source, key, from)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, chunk := range added {
+				chunk.ID = len(chunks) + 1
+				chunks = append(chunks, chunk)
+			}
+
+			var committed int64
+			for _, chunk := range chunks {
+				committed += chunk.Committed
+			}
+			logging.Infof("Retomando migração a partir do checkpoint de %s: %d/%d registros já gravados",
+				checkpoint.UpdatedAt.Format("2006-01-02 15:04:05"), committed, totalRows(chunks))
+			if len(added) > 0 {
+				logging.Infof("%d chunks novos com registros inseridos depois do checkpoint", len(added))
+			}
+			return chunks, checkpoint, nil
+		}
+	}
+	chunks, err := splitChunks(ctx, config, policy, source, key, math.MinInt64)
+	return chunks, nil, err
+}
+
+// splitChunks divide os registros com chave a partir de from em chunks de general.chunk_size registros
+func splitChunks(ctx context.Context, config *config.Config, policy retry.Policy, source models.Querier, key string, from int64) ([]*models.Chunk, error) {
+	var chunks []*models.Chunk
+	err := policy.Do(ctx, "divisão em chunks", func() error {
+		var err error
+		chunks, _, err = models.SplitChunks(ctx, source, config.MySQL.Table, key, from, chunkSizeOf(config))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao dividir a tabela em chunks: %v", err)
+	}
+	return chunks, nil
+}
+
+// chunkSizeOf retorna general.chunk_size ou o padrão
+func chunkSizeOf(config *config.Config) int64 {
+	if config.General.ChunkSize > 0 {
+		return int64(config.General.ChunkSize)
+	}
+	return defaultChunkSize
+}
+
+// totalRows soma os registros dos chunks
+func totalRows(chunks []*models.Chunk) int64 {
+	var total int64
+	for _, chunk := range chunks {
+		total += chunk.Rows
+	}
+	return total
+}
+
+// logRulesSummary mostra quantos registros cada regra descartou ou roteou
+func logRulesSummary(engine *rules.Engine) {
+	summary := engine.Summary()
+	if len(summary) == 0 {
+		return
+	}
+
+	logging.Info("")
+	logging.Info("Resumo das regras:")
+	for _, r := range summary {
+		if r.Action == rules.ActionRoute {
+			logging.Infof("  %s: %d registros roteados para '%s'", r.Name, r.Count, r.Collection)
+		} else {
+			logging.Infof("  %s: %d registros descartados", r.Name, r.Count)
+		}
+	}
+}
+
+// logDuplicatesSummary mostra o resultado do tratamento de CPFs duplicados
+func logDuplicatesSummary(resolver *duplicates.Resolver) {
+	if resolver == nil {
+		return
+	}
+
+	stats := resolver.Stats()
+	logging.Info("")
+	logging.Infof("CPFs duplicados: %d detectados, %d substituídos, %d mesclados, %d gravados em '%s'",
+		stats.Detected, stats.Replaced, stats.Merged, stats.Archived, resolver.ArchiveCollection())
+}
+
+// openDeadLetter abre o destino dos registros com falha conforme a configuração
+func openDeadLetter(ctx context.Context, config *config.Config, mongoClient *mongo.Client, clean bool) (*deadletter.Sink, error) {
+	if name := config.DeadLetter.Collection; name != "" {
+		collection := mongoClient.Database(config.MongoDB.Database).Collection(name)
+		if clean {
+			if err := collection.Drop(ctx); err != nil {
+				return nil, fmt.Errorf("erro ao limpar collection '%s': %v", name, err)
+			}
+		}
+		return deadletter.NewCollectionSink(collection), nil
+	}
+
+	path := config.DeadLetter.File
+	if path == "" {
+		path = deadLetterPath(config.General)
+	}
+	if clean {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("erro ao limpar arquivo de dead-letter: %v", err)
+		}
+	}
+	return deadletter.NewFileSink(path)
+}
+
+// checkFailureRate retorna erro se o percentual de registros com falha exceder dead_letter.max_failure_rate.
+// O percentual considera os registros processados, inclusive nas execuções retomadas.
+// Sem o limite configurado as falhas são apenas informadas
+func checkFailureRate(config *config.Config, deadLetter *deadletter.Sink, processed int64) error {
+	failures := deadLetter.Count()
+	if failures == 0 {
+		return nil
+	}
+
+	rate := 100.0
+	if processed > 0 {
+		rate = float64(failures) / float64(processed) * 100
+	}
+	logging.Info("")
+	logging.Infof("Registros com falha: %d (%.4f%%) gravados em %s", failures, rate, deadLetter.Destination())
+
+	limit := config.DeadLetter.MaxFailureRate
+	if limit != nil && rate > *limit {
+		return fmt.Errorf("taxa de falhas de %.4f%% acima do limite de %.4f%%", rate, *limit)
+	}
 	return nil
 }
+
+// interrupted grava o checkpoint, mostra o resumo por chunk e retorna ErrInterrupted
+func interrupted(path string, checkpoint Checkpoint) error {
+	logging.Info("")
+	logging.Info("Migração interrompida! Lotes em andamento foram gravados.")
+
+	var committed int64
+	done, pending := 0, 0
+	for _, chunk := range checkpoint.Chunks {
+		committed += chunk.Committed
+		switch {
+		case chunk.Status == models.ChunkDone:
+			done++
+		case chunk.Committed > 0:
+			logging.Infof("  Chunk %d (processador %d): %d/%d registros gravados (próxima chave: %d)",
+				chunk.ID, chunk.WorkerID, chunk.Committed, chunk.Rows, chunk.NextKey)
+			pending++
+		default:
+			pending++
+		}
+	}
+	logging.Infof("Chunks concluídos: %d - pendentes: %d", done, pending)
+	logging.Infof("Total gravado: %d/%d registros", committed, checkpoint.TotalRecords)
+
+	if err := saveCheckpoint(path, checkpoint); err != nil {
+		return fmt.Errorf("%w: %v", ErrInterrupted, err)
+	}
+	logging.Infof("Checkpoint salvo em %s (use \"resume\" para continuar)", path)
+
+	return ErrInterrupted
+}
@@ -0,0 +1,61 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/deadletter"
+)
+
+func TestCheckFailureRateUsesProcessedRecords(t *testing.T) {
+	limit := 1.0
+	cfg := &config.Config{}
+	cfg.DeadLetter.MaxFailureRate = &limit
+
+	sink, err := deadletter.NewFileSink(filepath.Join(t.TempDir(), "dead_letter.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	// Falhas de uma execução anterior, lidas do checkpoint
+	sink.Resume(2)
+
+	if err := checkFailureRate(cfg, sink, 1000); err != nil {
+		t.Fatalf("0,2%% de falhas não deveria exceder o limite: %v", err)
+	}
+	if err := checkFailureRate(cfg, sink, 100); err == nil {
+		t.Fatal("2% de falhas deveria exceder o limite de 1%")
+	}
+}
+
+func TestOpenDeadLetterKeepsFileOnResume(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.General.LogDir = t.TempDir()
+	cfg.General.Job = "pessoas"
+
+	path := filepath.Join(cfg.General.LogDir, "dead_letter_pessoas.jsonl")
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := openDeadLetter(context.Background(), cfg, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("a retomada não deveria apagar o dead-letter: %v", err)
+	}
+
+	sink, err = openDeadLetter(context.Background(), cfg, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("uma migração nova deveria apagar o dead-letter anterior: %v", err)
+	}
+}
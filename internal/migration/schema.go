@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Valores usados quando a configuração do validador não informa
+const (
+	defaultValidationLevel  = "strict"
+	defaultValidationAction = "error"
+)
+
+// Código retornado pelo collMod quando a collection não existe
+const namespaceNotFound = 26
+
+// Validator retorna o validador da collection principal, no formato {"$jsonSchema": {...}}
+func Validator(cfg *config.Config) bson.D {
+	return bson.D{{Key: "$jsonSchema", Value: models.JSONSchema(cfg.Mapping, cfg.Search)}}
+}
+
+// ApplyValidator aplica o validador à collection principal com collMod ou, se ela ainda não existir,
+// cria a collection com o validador
+func ApplyValidator(ctx context.Context, db *mongo.Database, cfg *config.Config) error {
+	name := cfg.MongoDB.Collection
+	level := cfg.Schema.ValidationLevel
+	if level == "" {
+		level = defaultValidationLevel
+	}
+	action := cfg.Schema.ValidationAction
+	if action == "" {
+		action = defaultValidationAction
+	}
+	validator := Validator(cfg)
+
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: name},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: level},
+		{Key: "validationAction", Value: action},
+	}).Err()
+
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == namespaceNotFound {
+		opts := options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel(level).
+			SetValidationAction(action)
+		if err := db.CreateCollection(ctx, name, opts); err != nil {
+			return fmt.Errorf("erro ao criar collection '%s' com o validador: %v", name, err)
+		}
+		logging.Infof("Collection '%s' criada com o validador $jsonSchema (level %s, action %s)", name, level, action)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao aplicar o validador na collection '%s': %v", name, err)
+	}
+	logging.Infof("Validador $jsonSchema aplicado na collection '%s' (level %s, action %s)", name, level, action)
+	return nil
+}
@@ -0,0 +1,722 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/models"
+	"MysqlToMongo/internal/rules"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Número máximo de diferenças detalhadas por tipo no relatório; as demais são apenas contadas
+const maxReportedDifferences = 1000
+
+// VerifyOptions representa as opções da verificação
+type VerifyOptions struct {
+	Sample    int    // Registros aleatórios reconvertidos e comparados campo a campo
+	Checksums bool   // Compara checksums de todos os chunks nos dois lados
+	Report    string // Caminho do relatório JSON; vazio usa tmp/logs/verify_<timestamp>.json
+}
+
+// FieldDifference representa um campo com valor diferente entre o esperado e o gravado
+type FieldDifference struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Stored   string `json:"stored"`
+}
+
+// Difference representa um documento ausente, sobrando ou diferente no MongoDB
+type Difference struct {
+	ID         string            `json:"id"`
+	Key        int64             `json:"key"` // Chave primária do registro de origem
+	Collection string            `json:"collection"`
+	Fields     []FieldDifference `json:"fields,omitempty"`
+}
+
+// ChunkChecksum representa os checksums de um chunk nos dois lados
+type ChunkChecksum struct {
+	ID       int    `json:"id"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Match    bool   `json:"match"`
+	Resolved bool   `json:"resolved,omitempty"` // Diferenças explicadas pelo tratamento de CPFs duplicados
+	Error    string `json:"error,omitempty"`
+}
+
+// VerifyReport representa o relatório de reconciliação
+type VerifyReport struct {
+	GeneratedAt     time.Time        `json:"generated_at"`
+	Table           string           `json:"table"`
+	Collection      string           `json:"collection"`
+	SourceCount     int64            `json:"source_count"`
+	TargetCounts    map[string]int64 `json:"target_counts"`
+	SampleChecked   int              `json:"sample_checked"`
+	SampleMismatch  int              `json:"sample_mismatched"`
+	Chunks          []ChunkChecksum  `json:"chunks,omitempty"`
+	DuplicatesCount int64            `json:"duplicates_count"` // Diferenças explicadas pelo tratamento de CPFs duplicados
+	MissingCount    int64            `json:"missing_count"`
+	ExtraCount      int64            `json:"extra_count"`
+	MismatchedCount int64            `json:"mismatched_count"`
+	Missing         []Difference     `json:"missing"`
+	Extra           []Difference     `json:"extra"`
+	Mismatched      []Difference     `json:"mismatched"`
+	OK              bool             `json:"ok"`
+
+	mu   sync.Mutex
+	seen map[string]bool // Diferenças já contadas, por tipo, collection e _id
+}
+
+// addMissing, addExtra e addMismatched contam a diferença e a guardam até o limite do relatório.
+// A amostra e os checksums podem encontrar o mesmo documento; cada _id é contado uma vez por tipo de diferença
+func (r *VerifyReport) addMissing(d Difference) {
+	r.add("missing", d, &r.MissingCount, &r.Missing)
+}
+
+func (r *VerifyReport) addExtra(d Difference) {
+	r.add("extra", d, &r.ExtraCount, &r.Extra)
+}
+
+func (r *VerifyReport) addMismatched(d Difference) {
+	r.add("mismatched", d, &r.MismatchedCount, &r.Mismatched)
+}
+
+func (r *VerifyReport) add(kind string, d Difference, count *int64, list *[]Difference) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.markSeen(kind, d.Collection, d.ID) {
+		return
+	}
+	*count++
+	if len(*list) < maxReportedDifferences {
+		*list = append(*list, d)
+	}
+}
+
+// addDuplicate conta um documento ausente ou diferente por causa do tratamento de CPFs duplicados
+func (r *VerifyReport) addDuplicate(collection, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.markSeen("duplicate", collection, id) {
+		r.DuplicatesCount++
+	}
+}
+
+// markSeen registra a diferença do documento e retorna false se ela já tiver sido contada
+func (r *VerifyReport) markSeen(kind, collection, id string) bool {
+	if r.seen == nil {
+		r.seen = make(map[string]bool)
+	}
+	key := kind + "\x00" + collection + "\x00" + id
+	if r.seen[key] {
+		return false
+	}
+	r.seen[key] = true
+	return true
+}
+
+// expectedDocument representa o documento que a migração deveria ter gravado para um registro
+type expectedDocument struct {
+	key        int64
+	collection string
+	raw        bson.Raw
+}
+
+// verifier reúne as conexões e a configuração usadas na verificação
+type verifier struct {
+	config      *config.Config
+	mysqlDB     *sql.DB
+	key         string // Coluna da chave primária
+	database    *mongo.Database
+	rules       *rules.Engine
+	collections []string // Collection principal, collections de roteamento e collection de duplicados
+	duplicates  string   // Estratégia de tratamento de CPFs duplicados usada na migração
+	archive     string   // Collection que recebe os CPFs duplicados descartados
+	report      *VerifyReport
+}
+
+// Verify compara a tabela de origem com o que foi gravado no MongoDB e grava o relatório de reconciliação.
+// O _id de cada documento é derivado da chave primária do registro, o que permite localizar o documento de cada linha
+func Verify(ctx context.Context, config *config.Config, mysqlDB *sql.DB, mongoClient *mongo.Client, opts VerifyOptions) (*VerifyReport, error) {
+	ruleEngine, err := rules.New(config.Rules, &models.OrderedDocument{})
+	if err != nil {
+		return nil, fmt.Errorf("erro nas regras de filtro: %v", err)
+	}
+	key, err := models.PrimaryKey(ctx, mysqlDB, config.MySQL.Database, config.MySQL.Table, config.MySQL.PrimaryKey)
+	if err != nil {
+		return nil, err
+	}
+	database := mongoClient.Database(config.MongoDB.Database)
+	resolver, err := duplicates.New(config.Duplicates, database, config.MongoDB.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &verifier{
+		config:      config,
+		mysqlDB:     mysqlDB,
+		key:         key,
+		database:    database,
+		rules:       ruleEngine,
+		collections: append([]string{config.MongoDB.Collection}, ruleEngine.Collections()...),
+		duplicates:  config.Duplicates.Strategy,
+		archive:     resolver.ArchiveCollection(),
+		report: &VerifyReport{
+			GeneratedAt:  time.Now(),
+			Table:        config.MySQL.Table,
+			Collection:   config.MongoDB.Collection,
+			TargetCounts: make(map[string]int64),
+			Missing:      []Difference{},
+			Extra:        []Difference{},
+			Mismatched:   []Difference{},
+		},
+	}
+
+	if v.archive != "" {
+		v.collections = append(v.collections, v.archive)
+	}
+
+	if err := v.compareCounts(ctx); err != nil {
+		return nil, err
+	}
+	if err := v.compareSample(ctx, opts.Sample); err != nil {
+		return nil, err
+	}
+	if opts.Checksums {
+		if err := v.compareChunks(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	report := v.report
+	report.OK = report.MissingCount == 0 && report.ExtraCount == 0 && report.MismatchedCount == 0 && report.SampleMismatch == 0
+	for _, chunk := range report.Chunks {
+		report.OK = report.OK && (chunk.Match || chunk.Resolved)
+	}
+
+	path := opts.Report
+	if path == "" {
+		path = logPath(config.General, "verify", "json")
+	}
+	if err := saveVerifyReport(path, report); err != nil {
+		return nil, err
+	}
+	logging.Infof("Relatório de reconciliação gravado em %s", path)
+	return report, nil
+}
+
+// compareCounts compara o total de registros da origem com o total de documentos de cada collection
+func (v *verifier) compareCounts(ctx context.Context) error {
+	var err error
+	v.report.SourceCount, err = countSource(ctx, v.mysqlDB, v.config.MySQL.Table)
+	if err != nil {
+		return err
+	}
+	v.report.TargetCounts, err = countCollections(ctx, v.database, v.collections)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, name := range v.collections {
+		total += v.report.TargetCounts[name]
+	}
+
+	logging.Infof("Contagem: %d registros no MySQL, %d documentos no MongoDB", v.report.SourceCount, total)
+	for _, name := range v.collections {
+		logging.Infof("  %s: %d", name, v.report.TargetCounts[name])
+	}
+	return nil
+}
+
+// compareSample reconverte registros aleatórios e compara com os documentos gravados campo a campo
+func (v *verifier) compareSample(ctx context.Context, sample int) error {
+	total := v.report.SourceCount
+	if sample <= 0 || total == 0 {
+		return nil
+	}
+	if int64(sample) > total {
+		sample = int(total)
+	}
+
+	// Sorteia chaves entre a menor e a maior e compara o primeiro registro a partir de cada uma
+	var minKey, maxKey int64
+	err := v.mysqlDB.QueryRowContext(ctx, fmt.Sprintf("SELECT MIN(`%s`), MAX(`%s`) FROM %s",
+		v.key, v.key, v.config.MySQL.Table)).Scan(&minKey, &maxKey)
+	if err != nil {
+		return fmt.Errorf("erro ao consultar o intervalo da chave: %v", err)
+	}
+
+	logging.Infof("Comparando %d registros aleatórios...", sample)
+	query := fmt.Sprintf("SELECT * FROM %s WHERE `%s` >= ? ORDER BY `%s` LIMIT 1", v.config.MySQL.Table, v.key, v.key)
+	for i := 0; i < sample; i++ {
+		from := minKey + int64(rand.Uint64()%(uint64(maxKey-minKey)+1))
+		expected, err := v.expectedDocuments(ctx, query, from)
+		if err != nil {
+			return err
+		}
+
+		v.report.SampleChecked++
+		mismatched := false
+		for _, exp := range expected {
+			stored, err := v.findDocument(ctx, exp)
+			if err != nil {
+				return err
+			}
+			differs, err := v.compareDocument(ctx, exp, stored)
+			if err != nil {
+				return err
+			}
+			mismatched = mismatched || differs
+		}
+		if mismatched {
+			v.report.SampleMismatch++
+		}
+	}
+	logging.Infof("Amostra: %d registros comparados, %d com diferenças", v.report.SampleChecked, v.report.SampleMismatch)
+	return nil
+}
+
+// findDocument busca o documento pelo _id na collection esperada e, se não estiver lá, nas demais collections
+func (v *verifier) findDocument(ctx context.Context, exp expectedDocument) (*expectedDocument, error) {
+	id := exp.raw.Lookup("_id")
+	names := append([]string{exp.collection}, v.collections...)
+	for i, name := range names {
+		if i > 0 && name == exp.collection {
+			continue
+		}
+		var stored bson.Raw
+		err := v.database.Collection(name).FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&stored)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar documento %s: %v", idHex(exp.raw), err)
+		}
+		return &expectedDocument{key: exp.key, collection: name, raw: stored}, nil
+	}
+	return nil, nil
+}
+
+// compareChunks calcula os checksums de cada chunk nos dois lados e detalha as diferenças dos chunks divergentes
+func (v *verifier) compareChunks(ctx context.Context) error {
+	chunks, _, err := models.SplitChunks(ctx, v.mysqlDB, v.config.MySQL.Table, v.key, math.MinInt64, chunkSizeOf(v.config))
+	if err != nil {
+		return fmt.Errorf("erro ao dividir a tabela em chunks: %v", err)
+	}
+	results := make([]ChunkChecksum, len(chunks))
+
+	numWorkers := v.config.General.NumWorkers.Value
+	if v.config.General.NumWorkers.IsAuto() {
+		numWorkers = autoNumWorkers()
+	}
+	logging.Infof("Calculando checksums de %d chunks com %d workers...", len(chunks), numWorkers)
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = v.compareChunk(ctx, chunks[i])
+			}
+		}()
+	}
+	for i := range chunks {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	matched := 0
+	for _, result := range results {
+		if result.Match {
+			matched++
+		}
+	}
+	v.report.Chunks = results
+	logging.Infof("Checksums: %d/%d chunks iguais", matched, len(results))
+
+	// Documentos com _id de chaves fora dos chunks não correspondem a nenhum registro
+	return v.findOuterExtras(ctx, chunks)
+}
+
+// compareChunk compara um chunk; quando os checksums divergem, registra cada documento ausente, sobrando ou diferente
+func (v *verifier) compareChunk(ctx context.Context, chunk *models.Chunk) ChunkChecksum {
+	result := ChunkChecksum{ID: chunk.ID, Start: chunk.Start, End: chunk.End}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE `%s` BETWEEN ? AND ? ORDER BY `%s`", v.config.MySQL.Table, v.key, v.key)
+	expected, err := v.expectedDocuments(ctx, query, chunk.Start, chunk.End)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	stored, err := v.storedDocuments(ctx, chunk.Start, chunk.End)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Source = checksum(expected)
+	result.Target = checksum(stored)
+	result.Match = result.Source == result.Target
+	if result.Match {
+		return result
+	}
+
+	// Detalha as diferenças pelo _id
+	byID := make(map[string]expectedDocument, len(stored))
+	for _, doc := range stored {
+		byID[doc.raw.Lookup("_id").String()] = doc
+	}
+	mismatched := false
+	for _, exp := range expected {
+		key := exp.raw.Lookup("_id").String()
+		var found *expectedDocument
+		if doc, ok := byID[key]; ok {
+			found = &doc
+		}
+		delete(byID, key)
+		differs, err := v.compareDocument(ctx, exp, found)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		mismatched = mismatched || differs
+	}
+	for _, extra := range byID {
+		v.report.addExtra(Difference{ID: idHex(extra.raw), Key: extra.key, Collection: extra.collection})
+		mismatched = true
+	}
+	result.Resolved = !mismatched
+	return result
+}
+
+// expectedDocuments lê os registros da consulta e os converte como na migração,
+// aplicando as regras para saber em qual collection cada documento deveria estar
+func (v *verifier) expectedDocuments(ctx context.Context, query string, args ...interface{}) ([]expectedDocument, error) {
+	rows, err := v.mysqlDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro na leitura do MySQL: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	keyIndex, err := models.KeyIndex(columns, v.key)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	var expected []expectedDocument
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+		key, err := models.KeyValue(values[keyIndex])
+		if err != nil {
+			return nil, fmt.Errorf("chave inválida na coluna '%s': %v", v.key, err)
+		}
+		doc, err := models.BuildDocument(values, v.config.Mapping)
+		if err != nil {
+			// Linhas que falham na conversão vão para o dead-letter e não têm documento
+			continue
+		}
+		doc.ID = models.DocumentID(v.config.MySQL.Table, key)
+		doc.SetSearchKeys(v.config.Search)
+
+		decision := v.rules.Evaluate(doc)
+		collection := v.config.MongoDB.Collection
+		switch decision.Action {
+		case rules.ActionSkip:
+			continue
+		case rules.ActionRoute:
+			collection = decision.Collection
+		}
+
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao serializar documento: %v", err)
+		}
+		expected = append(expected, expectedDocument{key: key, collection: collection, raw: raw})
+	}
+	return expected, rows.Err()
+}
+
+// storedDocuments busca nas collections os documentos cujo _id corresponde às chaves do intervalo
+func (v *verifier) storedDocuments(ctx context.Context, start, end int64) ([]expectedDocument, error) {
+	filter := bson.M{"_id": bson.M{
+		"$gte": models.DocumentID(v.config.MySQL.Table, start),
+		"$lte": models.DocumentID(v.config.MySQL.Table, end),
+	}}
+
+	var stored []expectedDocument
+	for _, name := range v.collections {
+		cursor, err := v.database.Collection(name).Find(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar documentos da collection '%s': %v", name, err)
+		}
+		for cursor.Next(ctx) {
+			raw := append(bson.Raw(nil), cursor.Current...)
+			stored = append(stored, expectedDocument{key: idKey(raw), collection: name, raw: raw})
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar documentos da collection '%s': %v", name, err)
+		}
+	}
+	return stored, nil
+}
+
+// findOuterExtras registra documentos com _id de chaves anteriores ao primeiro chunk ou posteriores ao último
+func (v *verifier) findOuterExtras(ctx context.Context, chunks []*models.Chunk) error {
+	type keyRange struct{ start, end int64 }
+	ranges := []keyRange{{math.MinInt64, math.MaxInt64}}
+	if len(chunks) > 0 {
+		first, last := chunks[0].Start, chunks[len(chunks)-1].End
+		ranges = ranges[:0]
+		if first > math.MinInt64 {
+			ranges = append(ranges, keyRange{math.MinInt64, first - 1})
+		}
+		if last < math.MaxInt64 {
+			ranges = append(ranges, keyRange{last + 1, math.MaxInt64})
+		}
+	}
+
+	for _, r := range ranges {
+		stored, err := v.storedDocuments(ctx, r.start, r.end)
+		if err != nil {
+			return err
+		}
+		for _, extra := range stored {
+			v.report.addExtra(Difference{ID: idHex(extra.raw), Key: extra.key, Collection: extra.collection})
+		}
+	}
+	return nil
+}
+
+// compareDocument compara o documento esperado com o encontrado pelo _id (nil se ausente) e registra a diferença.
+// Diferenças causadas pela estratégia de duplicados são apenas contadas. Retorna se alguma diferença foi registrada
+func (v *verifier) compareDocument(ctx context.Context, expected expectedDocument, stored *expectedDocument) (bool, error) {
+	if stored != nil && stored.collection == expected.collection && bytes.Equal(expected.raw, stored.raw) {
+		return false, nil
+	}
+	resolved, err := v.resolvedDuplicate(ctx, expected, stored)
+	if err != nil {
+		return false, err
+	}
+	if resolved {
+		v.report.addDuplicate(expected.collection, idHex(expected.raw))
+		return false, nil
+	}
+
+	diff := Difference{ID: idHex(expected.raw), Key: expected.key, Collection: expected.collection}
+	if stored == nil || stored.collection != expected.collection {
+		v.report.addMissing(diff)
+		if stored != nil {
+			v.report.addExtra(Difference{ID: idHex(stored.raw), Key: stored.key, Collection: stored.collection})
+		}
+		return true, nil
+	}
+
+	diff.Fields = compareFields(expected.raw, stored.raw)
+	if len(diff.Fields) == 0 {
+		return false, nil
+	}
+	v.report.addMismatched(diff)
+	return true, nil
+}
+
+// resolvedDuplicate verifica se a diferença é o resultado esperado da estratégia de duplicados.
+// O índice único de CPF existe apenas na collection principal
+func (v *verifier) resolvedDuplicate(ctx context.Context, expected expectedDocument, stored *expectedDocument) (bool, error) {
+	if v.duplicates == "" || expected.collection != v.config.MongoDB.Collection {
+		return false, nil
+	}
+	cpf := expected.raw.Lookup("cpf")
+	if cpf.Type == bsontype.Null || cpf.Type == 0 {
+		return false, nil
+	}
+
+	switch {
+	case stored != nil && stored.collection == v.archive:
+		// Registro descartado e arquivado com o mesmo _id
+		return bytes.Equal(expected.raw, stored.raw), nil
+	case stored == nil:
+		// Registro descartado ou que substituiu o documento de mesmo CPF gravado antes (keep_newest)
+		count, err := v.database.Collection(expected.collection).CountDocuments(ctx,
+			bson.D{{Key: "cpf", Value: cpf}}, options.Count().SetLimit(1))
+		if err != nil {
+			return false, fmt.Errorf("erro ao buscar CPF duplicado: %v", err)
+		}
+		return count > 0, nil
+	case stored.collection != expected.collection || !cpf.Equal(stored.raw.Lookup("cpf")):
+		return false, nil
+	}
+
+	switch v.duplicates {
+	case duplicates.StrategyKeepNewest:
+		// O documento foi substituído por um registro mais recente do mesmo CPF, mantendo o _id
+		return newerUpdate(stored.raw, expected.raw), nil
+	case duplicates.StrategyMergeContacts:
+		// O documento recebeu os contatos dos registros duplicados
+		for _, field := range compareFields(expected.raw, stored.raw) {
+			if field.Field != "contatos" {
+				return false, nil
+			}
+		}
+		return containsContacts(stored.raw, expected.raw, "telefones") && containsContacts(stored.raw, expected.raw, "emails"), nil
+	}
+	return false, nil
+}
+
+// newerUpdate verifica se data_atualizacao do documento gravado é posterior à do esperado
+func newerUpdate(stored, expected bson.Raw) bool {
+	storedTime, ok := stored.Lookup("data_atualizacao").DateTimeOK()
+	if !ok {
+		return false
+	}
+	expectedTime, ok := expected.Lookup("data_atualizacao").DateTimeOK()
+	return !ok || storedTime > expectedTime
+}
+
+// containsContacts verifica se a lista de contatos gravada contém todos os contatos esperados
+func containsContacts(stored, expected bson.Raw, list string) bool {
+	storedArray, _ := stored.Lookup("contatos", list).ArrayOK()
+	expectedArray, _ := expected.Lookup("contatos", list).ArrayOK()
+	storedValues, _ := storedArray.Values()
+	expectedValues, _ := expectedArray.Values()
+	for _, want := range expectedValues {
+		found := false
+		for _, value := range storedValues {
+			if value.Equal(want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compareFields retorna os campos de primeiro nível com valores diferentes entre os dois documentos
+func compareFields(expected, stored bson.Raw) []FieldDifference {
+	expectedElems, _ := expected.Elements()
+	storedElems, _ := stored.Elements()
+
+	values := make(map[string]bson.RawValue, len(storedElems))
+	for _, elem := range storedElems {
+		values[elem.Key()] = elem.Value()
+	}
+
+	var diffs []FieldDifference
+	for _, elem := range expectedElems {
+		key := elem.Key()
+		value, ok := values[key]
+		delete(values, key)
+		switch {
+		case !ok:
+			diffs = append(diffs, FieldDifference{Field: key, Expected: elem.Value().String(), Stored: "<ausente>"})
+		case !elem.Value().Equal(value):
+			diffs = append(diffs, FieldDifference{Field: key, Expected: elem.Value().String(), Stored: value.String()})
+		}
+	}
+
+	// Campos gravados que a conversão não gera
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		diffs = append(diffs, FieldDifference{Field: key, Expected: "<ausente>", Stored: values[key].String()})
+	}
+	return diffs
+}
+
+// checksum combina o hash de cada documento e da collection em que está, sem depender da ordem
+func checksum(docs []expectedDocument) string {
+	var sum uint64
+	for _, doc := range docs {
+		hash := fnv.New64a()
+		hash.Write([]byte(doc.collection))
+		hash.Write(doc.raw)
+		sum += hash.Sum64()
+	}
+	return fmt.Sprintf("%d:%016x", len(docs), sum)
+}
+
+// idHex retorna o _id do documento em hexadecimal
+func idHex(raw bson.Raw) string {
+	if _, data, ok := raw.Lookup("_id").BinaryOK(); ok {
+		return hex.EncodeToString(data)
+	}
+	if oid, ok := raw.Lookup("_id").ObjectIDOK(); ok {
+		return oid.Hex()
+	}
+	return raw.Lookup("_id").String()
+}
+
+// idKey extrai a chave do registro de origem de um _id gerado por DocumentID
+func idKey(raw bson.Raw) int64 {
+	subtype, data, ok := raw.Lookup("_id").BinaryOK()
+	if !ok {
+		return 0
+	}
+	key, _ := models.DocumentKey(primitive.Binary{Subtype: subtype, Data: data})
+	return key
+}
+
+// saveVerifyReport grava o relatório em JSON
+func saveVerifyReport(path string, report *VerifyReport) error {
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar relatório: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório do relatório: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar relatório: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package migration
+
+import (
+	"context"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestVerifyReportCountsEachDocumentOnce(t *testing.T) {
+	report := &VerifyReport{}
+	missing := Difference{ID: "0a", Key: 1, Collection: "pessoas"}
+
+	// A amostra e o checksum do chunk encontram o mesmo documento ausente
+	report.addMissing(missing)
+	report.addMissing(missing)
+	report.addMismatched(Difference{ID: "0b", Key: 2, Collection: "pessoas"})
+	report.addMismatched(Difference{ID: "0b", Key: 2, Collection: "pessoas"})
+	// O mesmo _id em outra collection é outra diferença
+	report.addExtra(Difference{ID: "0a", Key: 1, Collection: "pessoas_inativas"})
+	report.addDuplicate("pessoas", "0c")
+	report.addDuplicate("pessoas", "0c")
+
+	if report.MissingCount != 1 || len(report.Missing) != 1 {
+		t.Errorf("ausentes = %d (%d no relatório), esperado 1", report.MissingCount, len(report.Missing))
+	}
+	if report.MismatchedCount != 1 || len(report.Mismatched) != 1 {
+		t.Errorf("diferentes = %d (%d no relatório), esperado 1", report.MismatchedCount, len(report.Mismatched))
+	}
+	if report.ExtraCount != 1 {
+		t.Errorf("extras = %d, esperado 1", report.ExtraCount)
+	}
+	if report.DuplicatesCount != 1 {
+		t.Errorf("duplicados = %d, esperado 1", report.DuplicatesCount)
+	}
+}
+
+// rawDocument monta o documento como gravado pela migração
+func rawDocument(t *testing.T, key int64, fields bson.D) bson.Raw {
+	t.Helper()
+	data, err := bson.Marshal(append(bson.D{{Key: "_id", Value: models.DocumentID("pessoas", key)}}, fields...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCompareFields(t *testing.T) {
+	expected := rawDocument(t, 1, bson.D{{Key: "cpf", Value: "12345678901"}, {Key: "nome", Value: "Maria"}, {Key: "uf", Value: "MG"}})
+	stored := rawDocument(t, 1, bson.D{{Key: "cpf", Value: "12345678901"}, {Key: "nome", Value: "MARIA"}, {Key: "origem", Value: "manual"}})
+
+	got := compareFields(expected, stored)
+	want := []FieldDifference{
+		{Field: "nome", Expected: `"Maria"`, Stored: `"MARIA"`},
+		{Field: "uf", Expected: `"MG"`, Stored: "<ausente>"},
+		{Field: "origem", Expected: "<ausente>", Stored: `"manual"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareFields() = %+v, esperado %+v", got, want)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	first := expectedDocument{key: 1, collection: "pessoas", raw: rawDocument(t, 1, bson.D{{Key: "nome", Value: "Maria"}})}
+	second := expectedDocument{key: 2, collection: "pessoas", raw: rawDocument(t, 2, bson.D{{Key: "nome", Value: "João"}})}
+
+	if checksum([]expectedDocument{first, second}) != checksum([]expectedDocument{second, first}) {
+		t.Error("o checksum não deveria depender da ordem dos documentos")
+	}
+	moved := second
+	moved.collection = "pessoas_inativas"
+	if checksum([]expectedDocument{first, second}) == checksum([]expectedDocument{first, moved}) {
+		t.Error("o checksum deveria mudar quando um documento está em outra collection")
+	}
+}
+
+func TestIDKey(t *testing.T) {
+	raw := rawDocument(t, -42, nil)
+	if got := idKey(raw); got != -42 {
+		t.Errorf("idKey() = %d, esperado -42", got)
+	}
+	if got := idHex(raw); got != hex.EncodeToString(models.DocumentID("pessoas", -42).Data) {
+		t.Errorf("idHex() = %s", got)
+	}
+}
+
+func TestCompareDocument(t *testing.T) {
+	expected := expectedDocument{key: 1, collection: "pessoas", raw: rawDocument(t, 1, bson.D{{Key: "nome", Value: "Maria"}})}
+	changed := expectedDocument{key: 1, collection: "pessoas", raw: rawDocument(t, 1, bson.D{{Key: "nome", Value: "Mara"}})}
+	routed := expectedDocument{key: 1, collection: "pessoas_inativas", raw: expected.raw}
+
+	tests := []struct {
+		name                       string
+		stored                     *expectedDocument
+		want                       bool
+		missing, extra, mismatched int64
+	}{
+		{"igual", &expected, false, 0, 0, 0},
+		{"ausente", nil, true, 1, 0, 0},
+		{"em outra collection", &routed, true, 1, 1, 0},
+		{"diferente", &changed, true, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.MongoDB.Collection = "pessoas"
+			v := &verifier{config: cfg, report: &VerifyReport{}}
+
+			got, err := v.compareDocument(context.Background(), expected, tt.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := v.report
+			if got != tt.want || r.MissingCount != tt.missing || r.ExtraCount != tt.extra || r.MismatchedCount != tt.mismatched {
+				t.Errorf("compareDocument() = %v com %d ausentes, %d extras e %d diferentes; esperado %v, %d, %d e %d",
+					got, r.MissingCount, r.ExtraCount, r.MismatchedCount, tt.want, tt.missing, tt.extra, tt.mismatched)
+			}
+		})
+	}
+}
+
+func TestResolvedDuplicate(t *testing.T) {
+	older := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(1, 0, 0)
+	contacts := func(phones ...string) bson.D {
+		return bson.D{{Key: "telefones", Value: phones}, {Key: "emails", Value: bson.A{}}}
+	}
+	expected := expectedDocument{key: 1, collection: "pessoas", raw: rawDocument(t, 1, bson.D{
+		{Key: "cpf", Value: "12345678901"}, {Key: "data_atualizacao", Value: older}, {Key: "contatos", Value: contacts("3133334444")},
+	})}
+	stored := func(updated time.Time, phones ...string) *expectedDocument {
+		return &expectedDocument{key: 1, collection: "pessoas", raw: rawDocument(t, 1, bson.D{
+			{Key: "cpf", Value: "12345678901"}, {Key: "data_atualizacao", Value: updated}, {Key: "contatos", Value: contacts(phones...)},
+		})}
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		stored   *expectedDocument
+		want     bool
+	}{
+		{"substituído por registro mais recente", duplicates.StrategyKeepNewest, stored(newer, "3133334444"), true},
+		{"substituído por registro mais antigo", duplicates.StrategyKeepNewest, stored(older.AddDate(-1, 0, 0), "3133334444"), false},
+		{"contatos mesclados", duplicates.StrategyMergeContacts, stored(older, "3133334444", "31999998888"), true},
+		{"contato perdido", duplicates.StrategyMergeContacts, stored(older, "31999998888"), false},
+		{"sem estratégia", "", stored(newer, "3133334444"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.MongoDB.Collection = "pessoas"
+			v := &verifier{config: cfg, duplicates: tt.strategy, report: &VerifyReport{}}
+
+			got, err := v.resolvedDuplicate(context.Background(), expected, tt.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("resolvedDuplicate() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,31 +1,92 @@
 package migration
 
 import (
+	"bufio"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
 )
 
-// Função para calcular o limite de memória
+// Limite usado quando não é possível descobrir a memória da máquina
+const defaultMemoryLimit = int64(1 << 30)
+
+// Valores padrão da fila de chunks
+const (
+	defaultChunkSize     = 100000
+	defaultChunkAttempts = 3
+)
+
+// Capacidade padrão das filas entre os estágios do pipeline
+const defaultQueueBuffer = 10000
+
+// Limite máximo de workers no modo automático, para não esgotar as conexões do MySQL
+const maxAutoWorkers = 16
+
+// Função para calcular o limite de memória disponível para o processo.
+// Considera o limite do cgroup (containers) e, na falta dele, a memória disponível do sistema
 func calculateMemoryLimit() int64 {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	totalMemory := m.Sys
-	memoryLimit := int64(float64(totalMemory) * 0.7)
-	return memoryLimit
+	// cgroup v2
+	if limit, ok := readMemoryValue("/sys/fs/cgroup/memory.max"); ok {
+		return limit
+	}
+	// cgroup v1
+	if limit, ok := readMemoryValue("/sys/fs/cgroup/memory/memory.limit_in_bytes"); ok {
+		return limit
+	}
+	if available, ok := readMemAvailable(); ok {
+		return available
+	}
+	return defaultMemoryLimit
+}
+
+// readMemoryValue lê um limite de memória em bytes, ignorando valores que indicam "sem limite"
+func readMemoryValue(path string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	// "max" no cgroup v2 ou um valor gigantesco no cgroup v1 significam sem limite
+	if err != nil || value <= 0 || value >= 1<<62 {
+		return 0, false
+	}
+	if available, ok := readMemAvailable(); ok && available < value {
+		return available, true
+	}
+	return value, true
 }
 
-// Função para dividir o trabalho entre workers
-func SplitWork(totalRecords int64, numWorkers int) []struct{ Start, End int64 } {
-	chunkSize := totalRecords / int64(numWorkers)
-	chunks := make([]struct{ Start, End int64 }, numWorkers)
-
-	for i := 0; i < numWorkers; i++ {
-		chunks[i].Start = int64(i)*chunkSize + 1 // Começa do 1 para não pular registros
-		if i == numWorkers-1 {
-			chunks[i].End = totalRecords
-		} else {
-			chunks[i].End = chunks[i].Start + chunkSize - 1
+// readMemAvailable lê a memória disponível do sistema em /proc/meminfo
+func readMemAvailable() (int64, bool) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return kb * 1024, true
 		}
 	}
+	return 0, false
+}
 
-	return chunks
+// Função para definir o número de workers no modo automático
+func autoNumWorkers() int {
+	workers := runtime.NumCPU()
+	if workers > maxAutoWorkers {
+		workers = maxAutoWorkers
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
 }
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Parâmetros do cálculo automático do tamanho do lote
+const (
+	initialAutoBatchSize = 1000  // Tamanho usado até que haja amostras suficientes
+	minAutoBatchSize     = 100   // Menor lote permitido no modo automático
+	maxAutoBatchSize     = 50000 // Maior lote permitido no modo automático
+	sampleDocuments      = 5000  // Documentos medidos no início da migração
+	resizeInterval       = 500   // Recalcula o lote a cada N documentos medidos
+	memoryOverhead       = 3     // Documento convertido + linha de origem + serialização no driver
+)
+
+// BatchSizer define o tamanho dos lotes, fixo pela configuração ou calculado pelo tamanho
+// médio em BSON dos primeiros documentos e pela memória disponível
+type BatchSizer struct {
+	auto         bool
+	memoryBudget int64
+	workers      int64
+
+	current    atomic.Int64
+	sampled    atomic.Int64
+	totalBytes atomic.Int64
+}
+
+// NewBatchSizer cria o calculador. Com fixedSize > 0 o lote é sempre desse tamanho
+func NewBatchSizer(fixedSize int, memoryBudget int64, workers int) *BatchSizer {
+	sizer := &BatchSizer{
+		auto:         fixedSize <= 0,
+		memoryBudget: memoryBudget,
+		workers:      int64(workers),
+	}
+	if sizer.auto {
+		sizer.current.Store(initialAutoBatchSize)
+	} else {
+		sizer.current.Store(int64(fixedSize))
+	}
+	return sizer
+}
+
+// Size retorna o tamanho atual do lote
+func (b *BatchSizer) Size() int {
+	return int(b.current.Load())
+}
+
+// IsAuto indica se o lote está sendo calculado automaticamente
+func (b *BatchSizer) IsAuto() bool {
+	return b.auto
+}
+
+// AverageDocumentSize retorna o tamanho médio em bytes dos documentos medidos
+func (b *BatchSizer) AverageDocumentSize() int64 {
+	sampled := b.sampled.Load()
+	if sampled == 0 {
+		return 0
+	}
+	return b.totalBytes.Load() / sampled
+}
+
+// Observe mede o documento enquanto a amostragem estiver ativa e recalcula o lote
+func (b *BatchSizer) Observe(doc any) {
+	if !b.auto || b.sampled.Load() >= sampleDocuments {
+		return
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return
+	}
+	b.totalBytes.Add(int64(len(data)))
+	sampled := b.sampled.Add(1)
+
+	if sampled%resizeInterval == 0 {
+		b.resize()
+	}
+}
+
+// resize recalcula o lote dividindo a memória disponível entre os workers
+func (b *BatchSizer) resize() {
+	average := b.AverageDocumentSize()
+	if average == 0 {
+		return
+	}
+
+	size := b.memoryBudget / (b.workers * average * memoryOverhead)
+	if size < minAutoBatchSize {
+		size = minAutoBatchSize
+	}
+	if size > maxAutoBatchSize {
+		size = maxAutoBatchSize
+	}
+	b.current.Store(size)
+}
@@ -0,0 +1,228 @@
+package models
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Situações possíveis de um chunk
+const (
+	ChunkPending = "pending"
+	ChunkRunning = "running"
+	ChunkDone    = "done"
+	ChunkFailed  = "failed"
+)
+
+// Chunk representa um intervalo da chave primária processado por um worker de cada vez
+type Chunk struct {
+	ID        int    `json:"id"`
+	Start     int64  `json:"start"` // Primeira chave do intervalo
+	End       int64  `json:"end"`   // Última chave do intervalo, inclusive
+	Rows      int64  `json:"rows"`  // Registros do intervalo quando a tabela foi dividida
+	Status    string `json:"status"`
+	Committed int64  `json:"committed"` // Registros do início do chunk já gravados ou descartados
+	NextKey   int64  `json:"next_key"`  // Primeira chave ainda não gravada; a leitura é retomada a partir dela
+	Attempts  int    `json:"attempts"`
+	WorkerID  int    `json:"worker_id,omitempty"` // Leitor que processou o chunk por último
+	Error     string `json:"error,omitempty"`
+
+	read     int64 // Registros já lidos, incluindo os que ainda estão no pipeline
+	nextRead int64 // Chave seguinte à do último registro lido
+	inFlight int64 // Registros lidos que ainda não foram gravados ou descartados
+	ended    bool  // Leitura encerrada, aguardando os registros em andamento
+	outcome  error // Resultado da leitura, aplicado quando não houver registros em andamento
+	stopped  bool  // Leitura interrompida por sinal
+}
+
+// Subtipo binário definido pelo usuário usado nos _id gerados por DocumentID
+const DocumentIDSubtype byte = 0x80
+
+// Tamanho dos _id gerados por DocumentID: hash da tabela (4 bytes) seguido da chave (8 bytes)
+const documentIDSize = 12
+
+// DocumentID gera um _id binário determinístico a partir da tabela e da chave primária do registro,
+// para que reprocessar um chunk não duplique documentos já gravados. Não é um ObjectID: nenhum
+// byte representa data. Os 4 primeiros bytes são o FNV-32a do nome da tabela e os 8 seguintes a chave
+// em big-endian com o bit de sinal invertido. Como o MongoDB compara binários de mesmo tamanho e
+// subtipo byte a byte, a ordem dos _id de uma tabela é a mesma das chaves
+func DocumentID(table string, key int64) primitive.Binary {
+	data := make([]byte, documentIDSize)
+	hash := fnv.New32a()
+	hash.Write([]byte(table))
+	binary.BigEndian.PutUint32(data[0:4], hash.Sum32())
+	binary.BigEndian.PutUint64(data[4:12], uint64(key)^(1<<63))
+	return primitive.Binary{Subtype: DocumentIDSubtype, Data: data}
+}
+
+// DocumentKey retorna a chave primária do registro de origem de um _id gerado por DocumentID.
+// Retorna false se o _id não tiver esse formato
+func DocumentKey(id primitive.Binary) (int64, bool) {
+	if id.Subtype != DocumentIDSubtype || len(id.Data) != documentIDSize {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(id.Data[4:12]) ^ (1 << 63)), true
+}
+
+// ChunkQueue distribui os chunks sob demanda: cada leitor pega o próximo chunk pendente
+// assim que termina o anterior, e chunks com falha voltam para a fila até o limite de tentativas.
+// Um chunk só é concluído quando todos os seus registros passaram pela conversão e gravação
+type ChunkQueue struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	chunks      []*Chunk
+	maxAttempts int
+}
+
+// NewChunkQueue cria a fila com os chunks informados
+func NewChunkQueue(chunks []*Chunk, maxAttempts int) *ChunkQueue {
+	queue := &ChunkQueue{chunks: chunks, maxAttempts: maxAttempts}
+	queue.cond = sync.NewCond(&queue.mu)
+	return queue
+}
+
+// Next reserva o próximo chunk pendente para o leitor. Aguarda enquanto houver chunks em
+// andamento que possam voltar para a fila e retorna false quando não houver mais trabalho
+func (q *ChunkQueue) Next(ctx context.Context, workerID int) (*Chunk, bool) {
+	// Acorda os workers em espera quando o contexto for cancelado
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	})
+	defer stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for ctx.Err() == nil {
+		running := false
+		for _, chunk := range q.chunks {
+			switch chunk.Status {
+			case ChunkPending:
+				chunk.Status = ChunkRunning
+				chunk.WorkerID = workerID
+				chunk.Attempts++
+				chunk.read = chunk.Committed
+				chunk.nextRead = chunk.NextKey
+				chunk.ended = false
+				chunk.outcome = nil
+				chunk.stopped = false
+				return chunk, true
+			case ChunkRunning:
+				running = true
+			}
+		}
+		if !running {
+			return nil, false
+		}
+		q.cond.Wait()
+	}
+	return nil, false
+}
+
+// NextKey retorna a chave a partir da qual o chunk deve ser lido: a seguinte à do último registro
+// que entrou no pipeline
+func (q *ChunkQueue) NextKey(chunk *Chunk) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return chunk.nextRead
+}
+
+// AddRead registra um registro lido do chunk que entrou no pipeline, com a sua chave primária
+func (q *ChunkQueue) AddRead(chunk *Chunk, key int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chunk.read++
+	chunk.inFlight++
+	chunk.nextRead = key + 1
+}
+
+// Settle registra que n registros do chunk foram gravados ou descartados
+func (q *ChunkQueue) Settle(chunk *Chunk, n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chunk.inFlight -= n
+	q.complete(chunk)
+}
+
+// Finish encerra a leitura do chunk. O resultado só é aplicado quando todos os registros lidos
+// tiverem sido gravados: em caso de interrupção o chunk volta a ficar pendente; em caso de erro
+// ele volta para a fila até atingir o limite de tentativas. Retorna o número da tentativa encerrada
+func (q *ChunkQueue) Finish(chunk *Chunk, err error, interrupted bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chunk.ended = true
+	chunk.outcome = err
+	chunk.stopped = interrupted
+	attempt := chunk.Attempts
+	q.complete(chunk)
+	return attempt
+}
+
+// complete aplica o resultado da leitura quando não houver registros em andamento
+func (q *ChunkQueue) complete(chunk *Chunk) {
+	if !chunk.ended || chunk.inFlight > 0 || chunk.Status != ChunkRunning {
+		return
+	}
+	defer q.cond.Broadcast()
+
+	// Todos os registros lidos já foram gravados
+	chunk.Committed = chunk.read
+	chunk.NextKey = chunk.nextRead
+
+	switch {
+	case chunk.stopped:
+		chunk.Status = ChunkPending
+	case chunk.outcome == nil:
+		chunk.Status = ChunkDone
+		chunk.Error = ""
+	case chunk.Attempts < q.maxAttempts:
+		chunk.Status = ChunkPending
+		chunk.Error = chunk.outcome.Error()
+	default:
+		chunk.Status = ChunkFailed
+		chunk.Error = chunk.outcome.Error()
+	}
+}
+
+// Snapshot retorna uma cópia da situação atual de todos os chunks
+func (q *ChunkQueue) Snapshot() []Chunk {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	chunks := make([]Chunk, len(q.chunks))
+	for i, chunk := range q.chunks {
+		chunks[i] = *chunk
+	}
+	return chunks
+}
+
+// Committed retorna o total de registros já gravados em todos os chunks
+func (q *ChunkQueue) Committed() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var total int64
+	for _, chunk := range q.chunks {
+		total += chunk.Committed
+	}
+	return total
+}
+
+// Count retorna quantos chunks estão na situação informada
+func (q *ChunkQueue) Count(status string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count := 0
+	for _, chunk := range q.chunks {
+		if chunk.Status == status {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestChunkQueueFinish(t *testing.T) {
+	failure := errors.New("conexão perdida")
+	tests := []struct {
+		name          string
+		maxAttempts   int
+		attempts      int     // Tentativas anteriores do chunk
+		keys          []int64 // Chaves lidas nesta tentativa
+		err           error
+		interrupted   bool
+		wantStatus    string
+		wantCommitted int64
+		wantNextKey   int64
+		wantError     string
+	}{
+		{"leitura completa", 3, 0, []int64{10, 11, 15}, nil, false, ChunkDone, 3, 16, ""},
+		{"chunk sem registros", 3, 0, nil, nil, false, ChunkDone, 0, 10, ""},
+		{"erro volta para a fila", 3, 0, []int64{10, 12}, failure, false, ChunkPending, 2, 13, failure.Error()},
+		{"erro na última tentativa", 3, 2, []int64{10}, failure, false, ChunkFailed, 1, 11, failure.Error()},
+		{"interrupção não conta como falha", 1, 0, []int64{10}, nil, true, ChunkPending, 1, 11, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk := &Chunk{ID: 1, Start: 10, End: 20, NextKey: 10, Status: ChunkPending, Attempts: tt.attempts}
+			queue := NewChunkQueue([]*Chunk{chunk}, tt.maxAttempts)
+
+			got, ok := queue.Next(context.Background(), 1)
+			if !ok || got != chunk {
+				t.Fatalf("Next() = %v, %v; esperado o chunk pendente", got, ok)
+			}
+			for _, key := range tt.keys {
+				queue.AddRead(chunk, key)
+			}
+			queue.Settle(chunk, int64(len(tt.keys)))
+
+			if attempt := queue.Finish(chunk, tt.err, tt.interrupted); attempt != tt.attempts+1 {
+				t.Errorf("Finish() = %d, esperado %d", attempt, tt.attempts+1)
+			}
+			state := queue.Snapshot()[0]
+			if state.Status != tt.wantStatus || state.Committed != tt.wantCommitted || state.NextKey != tt.wantNextKey || state.Error != tt.wantError {
+				t.Errorf("chunk = {status %s, committed %d, next_key %d, error %q}, esperado {%s, %d, %d, %q}",
+					state.Status, state.Committed, state.NextKey, state.Error,
+					tt.wantStatus, tt.wantCommitted, tt.wantNextKey, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestChunkQueueWaitsForInFlightRecords(t *testing.T) {
+	chunk := &Chunk{ID: 1, Start: 1, End: 10, NextKey: 1, Status: ChunkPending}
+	queue := NewChunkQueue([]*Chunk{chunk}, 3)
+	queue.Next(context.Background(), 1)
+	queue.AddRead(chunk, 1)
+	queue.AddRead(chunk, 2)
+	queue.Settle(chunk, 1)
+	queue.Finish(chunk, nil, false)
+
+	if status := queue.Snapshot()[0].Status; status != ChunkRunning {
+		t.Fatalf("status com registro em andamento = %s, esperado %s", status, ChunkRunning)
+	}
+	if committed := queue.Committed(); committed != 0 {
+		t.Fatalf("Committed() com registro em andamento = %d, esperado 0", committed)
+	}
+
+	queue.Settle(chunk, 1)
+	state := queue.Snapshot()[0]
+	if state.Status != ChunkDone || state.Committed != 2 || state.NextKey != 3 {
+		t.Errorf("chunk = {status %s, committed %d, next_key %d}, esperado {done, 2, 3}", state.Status, state.Committed, state.NextKey)
+	}
+}
+
+func TestChunkQueueResumesFromNextKey(t *testing.T) {
+	chunk := &Chunk{ID: 1, Start: 1, End: 100, Committed: 40, NextKey: 57, Status: ChunkPending}
+	queue := NewChunkQueue([]*Chunk{chunk}, 3)
+	queue.Next(context.Background(), 1)
+
+	if key := queue.NextKey(chunk); key != 57 {
+		t.Errorf("NextKey() = %d, esperado 57", key)
+	}
+	queue.AddRead(chunk, 80)
+	queue.Settle(chunk, 1)
+	queue.Finish(chunk, nil, false)
+
+	state := queue.Snapshot()[0]
+	if state.Committed != 41 || state.NextKey != 81 {
+		t.Errorf("chunk = {committed %d, next_key %d}, esperado {41, 81}", state.Committed, state.NextKey)
+	}
+}
+
+func TestChunkQueueNext(t *testing.T) {
+	chunks := []*Chunk{
+		{ID: 1, Status: ChunkDone},
+		{ID: 2, Status: ChunkPending},
+		{ID: 3, Status: ChunkFailed},
+		{ID: 4, Status: ChunkPending},
+	}
+	queue := NewChunkQueue(chunks, 3)
+	ctx := context.Background()
+
+	first, _ := queue.Next(ctx, 1)
+	second, _ := queue.Next(ctx, 2)
+	if first.ID != 2 || second.ID != 4 {
+		t.Fatalf("Next() reservou os chunks %d e %d, esperado 2 e 4", first.ID, second.ID)
+	}
+	if second.WorkerID != 2 || second.Attempts != 1 {
+		t.Errorf("chunk 4 = {worker %d, attempts %d}, esperado {2, 1}", second.WorkerID, second.Attempts)
+	}
+
+	// Com chunks em andamento, Next aguarda até que um deles volte para a fila
+	next := make(chan *Chunk)
+	go func() {
+		chunk, _ := queue.Next(ctx, 3)
+		next <- chunk
+	}()
+	queue.Finish(first, nil, false)
+	queue.Finish(second, errors.New("falha"), false)
+
+	select {
+	case chunk := <-next:
+		if chunk == nil || chunk.ID != 4 || chunk.Attempts != 2 {
+			t.Fatalf("Next() após a falha = %+v, esperado o chunk 4 na tentativa 2", chunk)
+		}
+		queue.Finish(chunk, nil, false)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next() não retornou o chunk que voltou para a fila")
+	}
+
+	if chunk, ok := queue.Next(ctx, 1); ok {
+		t.Errorf("Next() sem chunks pendentes = %+v, esperado false", chunk)
+	}
+	if done := queue.Count(ChunkDone); done != 3 {
+		t.Errorf("Count(done) = %d, esperado 3", done)
+	}
+}
+
+func TestChunkQueueNextCanceled(t *testing.T) {
+	chunks := []*Chunk{{ID: 1, Status: ChunkPending}}
+	queue := NewChunkQueue(chunks, 3)
+	queue.Next(context.Background(), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan bool)
+	go func() {
+		_, ok := queue.Next(ctx, 2)
+		result <- ok
+	}()
+	cancel()
+
+	select {
+	case ok := <-result:
+		if ok {
+			t.Error("Next() com o contexto cancelado retornou um chunk")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next() não retornou após o cancelamento do contexto")
+	}
+}
+
+func TestDocumentID(t *testing.T) {
+	keys := []int64{math.MinInt64, -1000, -1, 0, 1, 255, 256, 1 << 40, math.MaxInt64}
+	for _, key := range keys {
+		if got, ok := DocumentKey(DocumentID("pessoas", key)); !ok || got != key {
+			t.Errorf("DocumentKey(DocumentID(%d)) = %d, %v", key, got, ok)
+		}
+	}
+
+	// A ordem dos _id é a mesma das chaves, para que intervalos de chaves virem intervalos de _id
+	ids := make([][]byte, len(keys))
+	for i, key := range keys {
+		ids[i] = DocumentID("pessoas", key).Data
+	}
+	if !sort.SliceIsSorted(ids, func(i, j int) bool { return bytes.Compare(ids[i], ids[j]) < 0 }) {
+		t.Error("a ordem dos _id não acompanha a ordem das chaves")
+	}
+
+	if DocumentID("pessoas", 1).Equal(DocumentID("clientes", 1)) {
+		t.Error("a mesma chave em tabelas diferentes gerou o mesmo _id")
+	}
+
+	// _id que não foram gerados por DocumentID não têm chave
+	if _, ok := DocumentKey(primitive.Binary{Data: make([]byte, 12)}); ok {
+		t.Error("DocumentKey aceitou um binário de outro subtipo")
+	}
+}
@@ -1,51 +1,219 @@
 package models
 
 import (
-	"sync"
+	"fmt"
 
 	"MysqlToMongo/internal/config"
-	"database/sql"
+	"MysqlToMongo/internal/converter"
+	"MysqlToMongo/internal/search"
 
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // OrderedDocument representa a estrutura ordenada do documento no MongoDB
 type OrderedDocument struct {
-	CPF             any `bson:"cpf"`
-	Nome            any `bson:"nome"`
-	Nasc            any `bson:"nasc"`
-	Renda           any `bson:"renda"`
-	AffinityScore   any `bson:"affinity_score"`
-	AffinityPercent any `bson:"affinity_percent"`
-	Sexo            any `bson:"sexo"`
-	CBO             any `bson:"cbo"`
-	Mae             any `bson:"mae"`
-	Nota            any `bson:"nota"`
-	Banco           any `bson:"banco"`
-	CPFConjuge      any `bson:"cpf_conjuge"`
-	ServPublico     any `bson:"serv_publico"`
-	DataObito       any `bson:"data_obito"`
-	Cidade          any `bson:"cidade"`
-	Endereco        any `bson:"endereco"`
-	Bairro          any `bson:"bairro"`
-	CEP             any `bson:"cep"`
-	UF              any `bson:"uf"`
-	DataAtualizacao any `bson:"data_atualizacao"`
+	ID              primitive.Binary `bson:"_id,omitempty"` // Gerado por DocumentID antes da inserção para que novas tentativas sejam idempotentes
+	CPF             any              `bson:"cpf"`
+	Nome            any              `bson:"nome"`
+	Nasc            any              `bson:"nasc"`
+	Renda           any              `bson:"renda"`
+	AffinityScore   any              `bson:"affinity_score"`
+	AffinityPercent any              `bson:"affinity_percent"`
+	Sexo            any              `bson:"sexo"`
+	CBO             any              `bson:"cbo"`
+	Mae             any              `bson:"mae"`
+	Nota            any              `bson:"nota"`
+	Banco           any              `bson:"banco"`
+	CPFConjuge      any              `bson:"cpf_conjuge"`
+	ServPublico     any              `bson:"serv_publico"`
+	DataObito       any              `bson:"data_obito"`
+	Cidade          any              `bson:"cidade"`
+	Endereco        any              `bson:"endereco"`
+	Bairro          any              `bson:"bairro"`
+	CEP             any              `bson:"cep"`
+	UF              any              `bson:"uf"`
+	DataAtualizacao any              `bson:"data_atualizacao"`
+	NomeBusca       any              `bson:"nome_busca,omitempty"`    // Nome normalizado para busca, se configurado
+	NomeFonetico    any              `bson:"nome_fonetico,omitempty"` // Chave fonética do nome, se configurado
 	Contatos        struct {
 		Telefones []any `bson:"telefones"`
 		Emails    []any `bson:"emails"`
 	} `bson:"contatos"`
 }
 
-// MigrationWorker representa um worker para processamento paralelo
-type MigrationWorker struct {
-	ID           int
-	StartID      int64
-	EndID        int64
-	MySQLDB      *sql.DB
-	MongoClient  *mongo.Client
-	Config       *config.Config
-	Wg           *sync.WaitGroup
-	ErrorChan    chan error
-	ProgressChan chan int
+// Field retorna o valor de um campo do documento pelo nome usado no MongoDB
+func (d *OrderedDocument) Field(name string) (any, bool) {
+	switch name {
+	case "cpf":
+		return d.CPF, true
+	case "nome":
+		return d.Nome, true
+	case "nasc":
+		return d.Nasc, true
+	case "renda":
+		return d.Renda, true
+	case "affinity_score":
+		return d.AffinityScore, true
+	case "affinity_percent":
+		return d.AffinityPercent, true
+	case "sexo":
+		return d.Sexo, true
+	case "cbo":
+		return d.CBO, true
+	case "mae":
+		return d.Mae, true
+	case "nota":
+		return d.Nota, true
+	case "banco":
+		return d.Banco, true
+	case "cpf_conjuge":
+		return d.CPFConjuge, true
+	case "serv_publico":
+		return d.ServPublico, true
+	case "data_obito":
+		return d.DataObito, true
+	case "cidade":
+		return d.Cidade, true
+	case "endereco":
+		return d.Endereco, true
+	case "bairro":
+		return d.Bairro, true
+	case "cep":
+		return d.CEP, true
+	case "uf":
+		return d.UF, true
+	case "data_atualizacao":
+		return d.DataAtualizacao, true
+	case "nome_busca":
+		return d.NomeBusca, true
+	case "nome_fonetico":
+		return d.NomeFonetico, true
+	case "contatos.telefones":
+		return d.Contatos.Telefones, true
+	case "contatos.emails":
+		return d.Contatos.Emails, true
+	}
+	return nil, false
+}
+
+// FieldMapping descreve de quais colunas e com qual conversor um campo do documento é preenchido
+type FieldMapping struct {
+	Field     string
+	Columns   []int // Posições das colunas na linha (a partir de 1)
+	Converter string
+}
+
+// FieldMappings retorna os campos do documento na ordem em que BuildDocument os preenche
+func FieldMappings(mapping *config.MappingConfig) []FieldMapping {
+	p := mapping.Pessoas
+	return []FieldMapping{
+		{"cpf", []int{p.CPF}, "ConvertBinaryToString"},
+		{"nome", []int{p.Nome}, "ConvertBinaryToString"},
+		{"nasc", []int{p.Nasc}, "ConvertToDatePtr"},
+		{"renda", []int{p.Renda}, "ConvertToDecimal"},
+		{"affinity_score", []int{p.AffinityScore}, "ConvertToDecimal"},
+		{"affinity_percent", []int{p.AffinityPercent}, "ConvertToDecimal"},
+		{"sexo", []int{p.Sexo}, "ConvertBinaryToString"},
+		{"cbo", []int{p.CBO}, "ConvertBinaryToString"},
+		{"mae", []int{p.Mae}, "ConvertBinaryToString"},
+		{"nota", []int{p.Nota}, "ConvertBinaryToString"},
+		{"banco", []int{p.Banco}, "ConvertBinaryToString"},
+		{"cpf_conjuge", []int{p.CPFConjuge}, "ConvertOptionalField"},
+		{"serv_publico", []int{p.ServPublico}, "ConvertOptionalField"},
+		{"data_obito", []int{p.DataObito}, "ConvertToDatePtr"},
+		{"cidade", []int{p.Cidade}, "ConvertBinaryToString"},
+		{"endereco", []int{p.Endereco}, "ConvertBinaryToString"},
+		{"bairro", []int{p.Bairro}, "ConvertOptionalField"},
+		{"cep", []int{p.CEP}, "ConvertBinaryToString"},
+		{"uf", []int{p.UF}, "ConvertBinaryToString"},
+		{"data_atualizacao", []int{p.DataAtualizacao}, "ConvertToTimePtr"},
+		{"contatos.telefones", p.Contatos.Telefones, "ConvertBinaryToString"},
+		{"contatos.emails", p.Contatos.Emails, "ConvertBinaryToString"},
+	}
+}
+
+// SetSearchKeys preenche os campos de busca por nome conforme a configuração
+func (d *OrderedDocument) SetSearchKeys(cfg config.SearchConfig) {
+	nome, ok := d.Nome.(string)
+	if !ok || nome == "" {
+		return
+	}
+	if cfg.NormalizedName {
+		d.NomeBusca = search.Normalize(nome)
+	}
+	if cfg.PhoneticName {
+		d.NomeFonetico = search.Phonetic(nome)
+	}
+}
+
+// BuildDocument converte uma linha do MySQL em documento conforme o mapeamento.
+// Retorna erro se o mapeamento referenciar uma coluna inexistente na linha
+func BuildDocument(values []interface{}, mapping *config.MappingConfig) (doc *OrderedDocument, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			doc = nil
+			err = fmt.Errorf("erro na conversão da linha: %v", r)
+		}
+	}()
+
+	doc = &OrderedDocument{}
+
+	p := mapping.Pessoas
+	// Convert CPF to string and pad with leading zeros
+	cpfStr := converter.ConvertBinaryToString(values[p.CPF-1])
+	if cpfStr != nil {
+		if str, ok := cpfStr.(string); ok {
+			// Pad with leading zeros to ensure 11 digits
+			doc.CPF = fmt.Sprintf("%011s", str)
+		} else {
+			doc.CPF = cpfStr
+		}
+	} else {
+		doc.CPF = nil
+	}
+	doc.Nome = converter.ConvertBinaryToString(values[p.Nome-1])
+	doc.Nasc = converter.ConvertToDatePtr(values[p.Nasc-1])
+	doc.Renda = converter.ConvertToDecimal(values[p.Renda-1])
+	doc.AffinityScore = converter.ConvertToDecimal(values[p.AffinityScore-1])
+	doc.AffinityPercent = converter.ConvertToDecimal(values[p.AffinityPercent-1])
+	doc.Sexo = converter.ConvertBinaryToString(values[p.Sexo-1])
+	doc.CBO = converter.ConvertBinaryToString(values[p.CBO-1])
+	doc.Mae = converter.ConvertBinaryToString(values[p.Mae-1])
+	doc.Nota = converter.ConvertBinaryToString(values[p.Nota-1])
+	doc.Banco = converter.ConvertBinaryToString(values[p.Banco-1])
+	doc.CPFConjuge = converter.ConvertOptionalField(values[p.CPFConjuge-1])
+	doc.ServPublico = converter.ConvertOptionalField(values[p.ServPublico-1])
+	doc.DataObito = converter.ConvertToDatePtr(values[p.DataObito-1])
+	doc.Cidade = converter.ConvertBinaryToString(values[p.Cidade-1])
+	doc.Endereco = converter.ConvertBinaryToString(values[p.Endereco-1])
+	doc.Bairro = converter.ConvertOptionalField(values[p.Bairro-1])
+	doc.CEP = converter.ConvertBinaryToString(values[p.CEP-1])
+	doc.UF = converter.ConvertBinaryToString(values[p.UF-1])
+	doc.DataAtualizacao = converter.ConvertToTimePtr(values[p.DataAtualizacao-1])
+
+	// Map telefones
+	telefones := make([]interface{}, 0)
+	for _, pos := range p.Contatos.Telefones {
+		if values[pos-1] != nil {
+			telefone := converter.ConvertBinaryToString(values[pos-1])
+			if str, ok := telefone.(string); ok && str != "" {
+				telefones = append(telefones, str)
+			}
+		}
+	}
+	doc.Contatos.Telefones = telefones
+
+	// Map emails
+	emails := make([]interface{}, 0)
+	for _, pos := range p.Contatos.Emails {
+		if values[pos-1] != nil {
+			email := converter.ConvertBinaryToString(values[pos-1])
+			if str, ok := email.(string); ok && str != "" {
+				emails = append(emails, str)
+			}
+		}
+	}
+	doc.Contatos.Emails = emails
+
+	return doc, nil
 }
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"MysqlToMongo/internal/config"
+)
+
+// Quantidade de exemplos guardados por campo e por tipo de erro no dry-run
+const dryRunExamples = 3
+
+// FieldStats representa as estatísticas de um campo do documento no dry-run
+type FieldStats struct {
+	Field     string
+	Converter string
+	Nulls     int64    // Documentos em que o campo ficou nulo ou vazio
+	Failures  int64    // Valores preenchidos na origem que o conversor não conseguiu converter
+	Examples  []string // Exemplos de valores de origem que falharam
+}
+
+// DryRunStats acumula as estatísticas da conversão sem gravar no MongoDB
+type DryRunStats struct {
+	mu          sync.Mutex
+	mappings    []FieldMapping
+	fields      []FieldStats
+	documents   int64
+	errors      int64
+	errorSample []string
+	samples     []*OrderedDocument
+	maxSamples  int
+}
+
+// NewDryRunStats cria o acumulador guardando até maxSamples documentos de exemplo
+func NewDryRunStats(mapping *config.MappingConfig, maxSamples int) *DryRunStats {
+	mappings := FieldMappings(mapping)
+	fields := make([]FieldStats, len(mappings))
+	for i, m := range mappings {
+		fields[i] = FieldStats{Field: m.Field, Converter: m.Converter}
+	}
+	return &DryRunStats{mappings: mappings, fields: fields, maxSamples: maxSamples}
+}
+
+// ObserveDocument registra os campos nulos e as falhas de conversão de um documento
+func (s *DryRunStats) ObserveDocument(doc *OrderedDocument, row []interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.documents++
+	if len(s.samples) < s.maxSamples {
+		s.samples = append(s.samples, doc)
+	}
+
+	for i, m := range s.mappings {
+		value, _ := doc.Field(m.Field)
+		if !isEmptyValue(value) {
+			continue
+		}
+		stats := &s.fields[i]
+		stats.Nulls++
+
+		if !conversionFailed(m, row) {
+			continue
+		}
+		stats.Failures++
+		if len(stats.Examples) < dryRunExamples {
+			stats.Examples = append(stats.Examples, formatSourceValue(row[m.Columns[0]-1]))
+		}
+	}
+}
+
+// conversionFailed indica se o campo, vazio no documento, tinha valor preenchido na origem.
+// Campos de várias colunas (contatos) e opcionais ficam vazios por definição
+func conversionFailed(m FieldMapping, row []interface{}) bool {
+	if len(m.Columns) != 1 || m.Converter == "ConvertOptionalField" {
+		return false
+	}
+	return !isEmptyValue(row[m.Columns[0]-1])
+}
+
+// ObserveFailure registra uma linha que não pôde ser convertida
+func (s *DryRunStats) ObserveFailure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors++
+	if len(s.errorSample) < dryRunExamples {
+		s.errorSample = append(s.errorSample, err.Error())
+	}
+}
+
+// Documents retorna quantos documentos foram convertidos
+func (s *DryRunStats) Documents() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.documents
+}
+
+// Errors retorna quantas linhas falharam na conversão e alguns exemplos dos erros
+func (s *DryRunStats) Errors() (int64, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errors, append([]string(nil), s.errorSample...)
+}
+
+// Fields retorna as estatísticas de cada campo
+func (s *DryRunStats) Fields() []FieldStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]FieldStats(nil), s.fields...)
+}
+
+// Samples retorna os documentos de exemplo
+func (s *DryRunStats) Samples() []*OrderedDocument {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*OrderedDocument(nil), s.samples...)
+}
+
+// isEmptyValue indica se o valor é nulo, uma string em branco ou uma lista vazia
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []byte:
+		return strings.TrimSpace(string(v)) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// formatSourceValue formata um valor de origem para os exemplos do relatório
+func formatSourceValue(value interface{}) string {
+	if bytes, ok := value.([]byte); ok {
+		return fmt.Sprintf("%q", string(bytes))
+	}
+	return fmt.Sprintf("%#v", value)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDryRunStats(t *testing.T) {
+	mapping := testMapping()
+	stats := NewDryRunStats(mapping, 1)
+
+	valid := make([]interface{}, 23)
+	valid[0], valid[1], valid[2] = []byte("12345678901"), []byte("Maria"), []byte("19800501")
+	// Data inválida na origem: o campo fica nulo e a falha é contada com o valor de origem
+	invalid := make([]interface{}, 23)
+	invalid[0], invalid[1], invalid[2] = []byte("98765432100"), []byte("João"), []byte("31/02/1980")
+
+	for _, row := range [][]interface{}{valid, invalid} {
+		doc, err := BuildDocument(row, mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats.ObserveDocument(doc, row)
+	}
+	for i := 0; i < 5; i++ {
+		stats.ObserveFailure(fmt.Errorf("linha %d: %w", i, errors.New("coluna inexistente")))
+	}
+
+	if got := stats.Documents(); got != 2 {
+		t.Errorf("Documents() = %d, esperado 2", got)
+	}
+	if got := len(stats.Samples()); got != 1 {
+		t.Errorf("%d documentos de exemplo, esperado o limite de 1", got)
+	}
+	errorCount, examples := stats.Errors()
+	if errorCount != 5 || len(examples) != dryRunExamples {
+		t.Errorf("Errors() = %d com %d exemplos, esperado 5 com %d", errorCount, len(examples), dryRunExamples)
+	}
+
+	fields := make(map[string]FieldStats)
+	for _, field := range stats.Fields() {
+		fields[field.Field] = field
+	}
+	if nome := fields["nome"]; nome.Nulls != 0 || nome.Failures != 0 {
+		t.Errorf("nome: %+v, esperado sem nulos", nome)
+	}
+	nasc := fields["nasc"]
+	if nasc.Nulls != 1 || nasc.Failures != 1 || len(nasc.Examples) != 1 || nasc.Examples[0] != `"31/02/1980"` {
+		t.Errorf("nasc: %+v, esperado uma falha com o valor de origem", nasc)
+	}
+	// Colunas nulas na origem não são falhas do conversor
+	if mae := fields["mae"]; mae.Nulls != 2 || mae.Failures != 0 {
+		t.Errorf("mae: %+v, esperado 2 nulos sem falhas", mae)
+	}
+	// Campos opcionais e contatos ficam vazios por definição
+	if bairro := fields["bairro"]; bairro.Failures != 0 {
+		t.Errorf("bairro: %+v, esperado sem falhas", bairro)
+	}
+	if telefones := fields["contatos.telefones"]; telefones.Nulls != 2 || telefones.Failures != 0 {
+		t.Errorf("contatos.telefones: %+v, esperado 2 vazios sem falhas", telefones)
+	}
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Tipos aceitos na chave primária usada para ler a tabela em ordem
+var integerTypes = map[string]bool{
+	"tinyint":   true,
+	"smallint":  true,
+	"mediumint": true,
+	"int":       true,
+	"integer":   true,
+	"bigint":    true,
+}
+
+// PrimaryKey retorna a coluna usada para ler a tabela em ordem: a informada em mysql.primary_key ou a chave
+// primária da tabela. A coluna precisa ser inteira e única; chaves compostas não são suportadas
+func PrimaryKey(ctx context.Context, q Querier, database, table, configured string) (string, error) {
+	column := configured
+	if column == "" {
+		names, err := queryStrings(ctx, q, `SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+			ORDER BY ORDINAL_POSITION`, database, table)
+		if err != nil {
+			return "", fmt.Errorf("erro ao consultar a chave primária: %v", err)
+		}
+		switch len(names) {
+		case 0:
+			return "", fmt.Errorf("a tabela '%s' não tem chave primária; informe em mysql.primary_key uma coluna inteira e única", table)
+		case 1:
+			column = names[0]
+		default:
+			return "", fmt.Errorf("a chave primária de '%s' é composta (%s); informe em mysql.primary_key uma coluna inteira e única",
+				table, strings.Join(names, ", "))
+		}
+	}
+
+	types, err := queryStrings(ctx, q, `SELECT DATA_TYPE FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?`, database, table, column)
+	if err != nil {
+		return "", fmt.Errorf("erro ao consultar a coluna '%s': %v", column, err)
+	}
+	if len(types) == 0 {
+		return "", fmt.Errorf("a coluna '%s' da chave não existe na tabela '%s'", column, table)
+	}
+	if !integerTypes[strings.ToLower(types[0])] {
+		return "", fmt.Errorf("a chave '%s' é do tipo %s; a leitura em ordem exige uma coluna inteira", column, types[0])
+	}
+	return column, nil
+}
+
+// queryStrings retorna a primeira coluna de cada linha da consulta
+func queryStrings(ctx context.Context, q Querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// SplitChunks divide os registros com chave a partir de from em chunks de até chunkSize registros.
+// O fim de cada chunk é encontrado pelo índice da chave, saltando chunkSize-1 registros a partir do início,
+// sem trazer as chaves intermediárias. Os intervalos são contíguos, para que registros inseridos entre
+// dois chunks depois da divisão também sejam lidos. Retorna também o total de registros
+func SplitChunks(ctx context.Context, q Querier, table, key string, from, chunkSize int64) ([]*Chunk, int64, error) {
+	first := fmt.Sprintf("SELECT MIN(`%s`) FROM %s WHERE `%s` >= ?", key, table, key)
+	boundary := fmt.Sprintf("SELECT `%s` FROM %s WHERE `%s` >= ? ORDER BY `%s` LIMIT 1 OFFSET %d", key, table, key, key, chunkSize-1)
+	remaining := fmt.Sprintf("SELECT COUNT(*), MAX(`%s`) FROM %s WHERE `%s` >= ?", key, table, key)
+
+	start, ok, err := queryKey(ctx, q, first, from)
+	if err != nil || !ok {
+		return nil, 0, err
+	}
+
+	var chunks []*Chunk
+	var total int64
+	for {
+		rows := chunkSize
+		end, ok, err := queryKey(ctx, q, boundary, start)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			// Restam menos de chunkSize registros: o último chunk vai até a maior chave
+			if rows, end, err = queryRemaining(ctx, q, remaining, start); err != nil {
+				return nil, 0, err
+			}
+			if rows == 0 {
+				break
+			}
+		}
+
+		chunks = append(chunks, &Chunk{ID: len(chunks) + 1, Start: start, End: end, Rows: rows, NextKey: start, Status: ChunkPending})
+		total += rows
+		if rows < chunkSize || end == math.MaxInt64 {
+			break
+		}
+		start = end + 1
+	}
+	return chunks, total, nil
+}
+
+// queryKey retorna a chave da primeira linha da consulta; false se não houver linha ou a chave for nula
+func queryKey(ctx context.Context, q Querier, query string, args ...interface{}) (int64, bool, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	var key sql.NullInt64
+	if rows.Next() {
+		if err := rows.Scan(&key); err != nil {
+			return 0, false, err
+		}
+	}
+	return key.Int64, key.Valid, rows.Err()
+}
+
+// queryRemaining retorna quantos registros restam a partir de start e a maior chave entre eles
+func queryRemaining(ctx context.Context, q Querier, query string, start int64) (int64, int64, error) {
+	rows, err := q.QueryContext(ctx, query, start)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	var last sql.NullInt64
+	if rows.Next() {
+		if err := rows.Scan(&count, &last); err != nil {
+			return 0, 0, err
+		}
+	}
+	return count, last.Int64, rows.Err()
+}
+
+// KeyIndex retorna a posição da coluna da chave entre as colunas retornadas pelo SELECT *
+func KeyIndex(columns []string, key string) (int, error) {
+	for i, column := range columns {
+		if strings.EqualFold(column, key) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("a coluna '%s' da chave não foi retornada pela consulta", key)
+}
+
+// KeyValue converte o valor lido da coluna da chave. O driver retorna inteiros como int64
+// em consultas com parâmetros e como texto nas demais
+func KeyValue(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("chave %d acima do maior inteiro suportado", v)
+		}
+		return int64(v), nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case nil:
+		return 0, fmt.Errorf("chave nula")
+	}
+	return 0, fmt.Errorf("chave de tipo não suportado: %T", value)
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    int64
+		wantErr bool
+	}{
+		{"int64", int64(42), 42, false},
+		{"int32", int32(-7), -7, false},
+		{"uint64", uint64(1 << 40), 1 << 40, false},
+		{"uint64 acima do limite", uint64(math.MaxUint64), 0, true},
+		{"texto do protocolo textual", []byte("123456"), 123456, false},
+		{"string", "-15", -15, false},
+		{"texto inválido", []byte("abc"), 0, true},
+		{"nulo", nil, 0, true},
+		{"tipo não suportado", 1.5, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KeyValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KeyValue(%#v) erro = %v, esperado erro: %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("KeyValue(%#v) = %d, esperado %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyIndex(t *testing.T) {
+	columns := []string{"id_pessoa", "CPF", "nome"}
+	tests := []struct {
+		key     string
+		want    int
+		wantErr bool
+	}{
+		{"id_pessoa", 0, false},
+		{"cpf", 1, false},
+		{"id", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := KeyIndex(columns, tt.key)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("KeyIndex(%q) = %d, %v; esperado %d, erro: %v", tt.key, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// keysDriver simula as consultas de SplitChunks sobre uma lista ordenada de chaves
+type keysDriver struct {
+	keys    []int64
+	queries []string
+}
+
+func (d *keysDriver) Open(string) (driver.Conn, error) { return keysConn{d}, nil }
+
+type keysConn struct{ d *keysDriver }
+
+func (c keysConn) Prepare(query string) (driver.Stmt, error) { return keysStmt{c.d, query}, nil }
+func (c keysConn) Close() error                              { return nil }
+func (c keysConn) Begin() (driver.Tx, error)                 { return nil, errors.New("sem transações") }
+
+type keysStmt struct {
+	d     *keysDriver
+	query string
+}
+
+func (s keysStmt) Close() error  { return nil }
+func (s keysStmt) NumInput() int { return -1 }
+func (s keysStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("sem escrita")
+}
+
+func (s keysStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	from := args[0].(int64)
+	var keys []int64
+	for _, key := range s.d.keys {
+		if key >= from {
+			keys = append(keys, key)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(s.query, "SELECT MIN("):
+		if len(keys) == 0 {
+			return &keysRows{columns: 1, values: [][]driver.Value{{nil}}}, nil
+		}
+		return &keysRows{columns: 1, values: [][]driver.Value{{keys[0]}}}, nil
+	case strings.HasPrefix(s.query, "SELECT COUNT(*)"):
+		if len(keys) == 0 {
+			return &keysRows{columns: 2, values: [][]driver.Value{{int64(0), nil}}}, nil
+		}
+		return &keysRows{columns: 2, values: [][]driver.Value{{int64(len(keys)), keys[len(keys)-1]}}}, nil
+	}
+
+	_, offsetText, _ := strings.Cut(s.query, "OFFSET ")
+	offset, err := strconv.Atoi(offsetText)
+	if err != nil {
+		return nil, fmt.Errorf("consulta inesperada: %s", s.query)
+	}
+	if offset >= len(keys) {
+		return &keysRows{columns: 1}, nil
+	}
+	return &keysRows{columns: 1, values: [][]driver.Value{{keys[offset]}}}, nil
+}
+
+type keysRows struct {
+	columns int
+	values  [][]driver.Value
+}
+
+func (r *keysRows) Columns() []string { return make([]string, r.columns) }
+func (r *keysRows) Close() error      { return nil }
+func (r *keysRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      []int64
+		from      int64
+		chunkSize int64
+		want      [][3]int64 // Início, fim e registros de cada chunk
+	}{
+		{"tabela vazia", nil, math.MinInt64, 3, nil},
+		{"divisão exata", []int64{1, 2, 3, 4, 5, 6}, math.MinInt64, 3, [][3]int64{{1, 3, 3}, {4, 6, 3}}},
+		{"último chunk menor", []int64{1, 5, 9, 20, 21}, math.MinInt64, 2, [][3]int64{{1, 5, 2}, {6, 20, 2}, {21, 21, 1}}},
+		{"a partir de uma chave", []int64{1, 5, 9, 20, 21}, 6, 2, [][3]int64{{9, 20, 2}, {21, 21, 1}}},
+		{"nada a partir da chave", []int64{1, 5}, 6, 2, nil},
+		{"chave máxima", []int64{-3, math.MaxInt64}, math.MinInt64, 2, [][3]int64{{-3, math.MaxInt64, 2}}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &keysDriver{keys: tt.keys}
+			name := fmt.Sprintf("keys-%d", i)
+			sql.Register(name, fake)
+			db, err := sql.Open(name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			chunks, total, err := SplitChunks(context.Background(), db, "pessoas", "id", tt.from, tt.chunkSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][3]int64
+			var rows int64
+			for _, chunk := range chunks {
+				got = append(got, [3]int64{chunk.Start, chunk.End, chunk.Rows})
+				rows += chunk.Rows
+				if chunk.NextKey != chunk.Start || chunk.Status != ChunkPending {
+					t.Errorf("chunk %d não começa pendente no início: %+v", chunk.ID, chunk)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitChunks() = %v, esperado %v", got, tt.want)
+			}
+			if total != rows {
+				t.Errorf("total = %d, esperado %d", total, rows)
+			}
+
+			// Cada chunk custa uma consulta pelo índice, sem percorrer as chaves
+			for _, query := range fake.queries {
+				if !strings.Contains(query, "LIMIT 1") && !strings.HasPrefix(query, "SELECT MIN(") && !strings.HasPrefix(query, "SELECT COUNT(*)") {
+					t.Errorf("consulta percorre as chaves: %s", query)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"MysqlToMongo/internal/config"
+)
+
+// CheckMapping verifica as posições do mapeamento, retornando todos os problemas encontrados:
+// colunas não mapeadas e a mesma coluna usada por mais de um campo.
+// Com as colunas da tabela de origem, verifica também se as posições existem na tabela
+func CheckMapping(mapping *config.MappingConfig, columns []string) config.Problems {
+	var problems config.Problems
+	if mapping == nil {
+		return problems
+	}
+
+	usedBy := make(map[int][]string)
+	for _, m := range FieldMappings(mapping) {
+		for i, position := range m.Columns {
+			field := m.Field
+			if len(m.Columns) > 1 || strings.HasPrefix(field, "contatos.") {
+				field = fmt.Sprintf("%s[%d]", m.Field, i)
+			}
+			switch {
+			case position == 0:
+				problems.Add("mapping %s: coluna não mapeada", field)
+				continue
+			case position < 0:
+				problems.Add("mapping %s: posição %d inválida, as colunas começam em 1", field, position)
+				continue
+			case columns != nil && position > len(columns):
+				problems.Add("mapping %s: coluna %d não existe, a tabela tem %d colunas", field, position, len(columns))
+				continue
+			}
+			usedBy[position] = append(usedBy[position], field)
+		}
+	}
+
+	positions := make([]int, 0, len(usedBy))
+	for position, fields := range usedBy {
+		if len(fields) > 1 {
+			positions = append(positions, position)
+		}
+	}
+	sort.Ints(positions)
+	for _, position := range positions {
+		problems.Add("mapping: coluna %d%s usada por mais de um campo: %s",
+			position, columnName(columns, position), strings.Join(usedBy[position], ", "))
+	}
+	return problems
+}
+
+// columnName retorna o nome da coluna entre parênteses, se as colunas da tabela forem conhecidas
+func columnName(columns []string, position int) string {
+	if position < 1 || position > len(columns) {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", columns[position-1])
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/deadletter"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/retry"
+	"MysqlToMongo/internal/rules"
+	"MysqlToMongo/internal/throttle"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// rowItem representa uma linha lida do MySQL a caminho da conversão
+type rowItem struct {
+	chunk   *Chunk
+	key     int64 // Chave primária do registro
+	columns []string
+	row     []interface{}
+}
+
+// pendingDocument representa um documento convertido a caminho da gravação, junto com a linha de origem
+type pendingDocument struct {
+	chunk   *Chunk
+	columns []string
+	row     []interface{}
+	doc     *OrderedDocument
+	target  string // Collection de roteamento; vazio para a collection principal
+}
+
+// Pipeline conecta os estágios de leitura, conversão e gravação por filas com capacidade limitada,
+// para que o MySQL continue sendo lido enquanto o MongoDB grava e cada estágio escale separadamente
+type Pipeline struct {
+	Chunks         *ChunkQueue
+	MySQLDB        *sql.DB
+	SnapshotConns  []*sql.Conn // Conexão de cada leitor no modo snapshot; vazio usa MySQLDB
+	Key            string      // Coluna da chave primária, usada para ler cada chunk em ordem
+	MongoClient    *mongo.Client
+	Config         *config.Config
+	Rules          *rules.Engine
+	Duplicates     *duplicates.Resolver
+	DeadLetter     *deadletter.Sink
+	Retry          retry.Policy
+	Throttle       *throttle.Throttler
+	BatchSizer     *BatchSizer
+	DryRun         *DryRunStats // Quando informado, converte sem gravar no MongoDB
+	Readers        int
+	Converters     int
+	Writers        int
+	RowBuffer      int
+	DocumentBuffer int
+	ProgressChan   chan int
+
+	mappings []FieldMapping // Campos do mapeamento, para contar as falhas de conversão
+	rows     chan rowItem
+	docs     chan pendingDocument
+	done     chan struct{}
+	err      error
+}
+
+// Querier executa consultas no MySQL, seja pelo pool ou por uma conexão do snapshot
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// source retorna onde o leitor deve consultar o MySQL
+func (p *Pipeline) source(id int) Querier {
+	if len(p.SnapshotConns) > 0 {
+		return p.SnapshotConns[id-1]
+	}
+	return p.MySQLDB
+}
+
+// Start cria as filas e inicia as goroutines de cada estágio.
+// Se o contexto for cancelado os leitores param, e o que já foi lido é convertido e gravado
+func (p *Pipeline) Start(ctx context.Context) {
+	p.mappings = FieldMappings(p.Config.Mapping)
+	p.rows = make(chan rowItem, p.RowBuffer)
+	p.docs = make(chan pendingDocument, p.DocumentBuffer)
+	p.done = make(chan struct{})
+
+	// Erros fatais de qualquer estágio encerram a leitura
+	readCtx, cancel := context.WithCancelCause(ctx)
+
+	// Conversão e gravação não são canceladas para que as filas sejam esvaziadas na interrupção
+	writeCtx := context.WithoutCancel(ctx)
+
+	var readers, converters, writers sync.WaitGroup
+	for i := 1; i <= p.Readers; i++ {
+		readers.Add(1)
+		go func(id int) {
+			defer readers.Done()
+			if err := p.read(readCtx, id); err != nil {
+				cancel(err)
+			}
+		}(i)
+	}
+	for i := 1; i <= p.Converters; i++ {
+		converters.Add(1)
+		go func(id int) {
+			defer converters.Done()
+			if err := p.convert(writeCtx, id); err != nil {
+				cancel(err)
+				// Continua consumindo a fila para não bloquear os leitores
+				for range p.rows {
+				}
+			}
+		}(i)
+	}
+	for i := 1; i <= p.Writers; i++ {
+		writers.Add(1)
+		go func(id int) {
+			defer writers.Done()
+			if err := p.write(writeCtx, id); err != nil {
+				cancel(err)
+				// Continua consumindo a fila para não bloquear os conversores
+				for range p.docs {
+				}
+			}
+		}(i)
+	}
+
+	// Cada fila é fechada quando o estágio que a alimenta termina
+	go func() {
+		readers.Wait()
+		close(p.rows)
+		converters.Wait()
+		close(p.docs)
+		writers.Wait()
+
+		if ctx.Err() == nil {
+			p.err = context.Cause(readCtx)
+		}
+		cancel(nil)
+		close(p.done)
+	}()
+}
+
+// Wait aguarda o fim de todos os estágios e retorna o primeiro erro fatal
+func (p *Pipeline) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// QueueDepths retorna a ocupação das filas entre os estágios, para evidenciar o gargalo
+func (p *Pipeline) QueueDepths() (rows, documents int) {
+	return len(p.rows), len(p.docs)
+}
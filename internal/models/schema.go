@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"MysqlToMongo/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Tipos BSON que cada conversor pode gravar. ConvertBinaryToString e ConvertOptionalField mantêm
+// como números os inteiros e decimais que o protocolo binário do MySQL entrega como int64 e float64
+var converterTypes = map[string][]string{
+	"ConvertBinaryToString": {"string", "long", "double", "null"},
+	"ConvertToTimePtr":      {"date", "null"},
+	"ConvertToDatePtr":      {"date", "null"},
+	"ConvertToDecimal":      {"decimal", "null"},
+	"ConvertOptionalField":  {"string", "int", "long", "double", "null"},
+}
+
+// Formato exigido de alguns campos de texto. CEP e UF aceitam vazio, gravado quando a coluna está em branco
+var fieldPatterns = map[string]string{
+	"cpf": `^[0-9]{11}$`,
+	"cep": `^([0-9]{5}-?[0-9]{3})?$`,
+	"uf":  `^([A-Za-z]{2})?$`,
+}
+
+// JSONSchema gera o validador $jsonSchema dos documentos gravados conforme o mapeamento: tipos de cada campo,
+// campos obrigatórios, formato de cpf, cep e uf e o tipo dos itens dos contatos.
+// Todos os campos são gravados em todo documento, com null quando não há valor
+func JSONSchema(mapping *config.MappingConfig, search config.SearchConfig) bson.D {
+	required := bson.A{"_id"}
+	properties := bson.D{{Key: "_id", Value: bson.D{{Key: "bsonType", Value: "binData"}}}}
+	contatos := bson.D{}
+
+	for _, m := range FieldMappings(mapping) {
+		property := bson.D{}
+		if name, ok := strings.CutPrefix(m.Field, "contatos."); ok {
+			property = append(property,
+				bson.E{Key: "bsonType", Value: "array"},
+				bson.E{Key: "items", Value: bson.D{{Key: "bsonType", Value: "string"}}},
+				bson.E{Key: "description", Value: fmt.Sprintf("colunas %s (%s)", joinInts(m.Columns), m.Converter)},
+			)
+			contatos = append(contatos, bson.E{Key: name, Value: property})
+			continue
+		}
+
+		if types := converterTypes[m.Converter]; len(types) > 0 {
+			property = append(property, bson.E{Key: "bsonType", Value: bsonTypes(types)})
+		}
+		if pattern, ok := fieldPatterns[m.Field]; ok {
+			property = append(property, bson.E{Key: "pattern", Value: pattern})
+		}
+		property = append(property, bson.E{Key: "description", Value: fmt.Sprintf("coluna %s (%s)", joinInts(m.Columns), m.Converter)})
+
+		required = append(required, m.Field)
+		properties = append(properties, bson.E{Key: m.Field, Value: property})
+	}
+
+	contatosRequired := bson.A{}
+	for _, e := range contatos {
+		contatosRequired = append(contatosRequired, e.Key)
+	}
+	required = append(required, "contatos")
+	properties = append(properties, bson.E{Key: "contatos", Value: bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: contatosRequired},
+		{Key: "properties", Value: contatos},
+	}})
+
+	// Campos de busca por nome são gravados apenas quando configurados e o nome não está vazio
+	if search.NormalizedName {
+		properties = append(properties, bson.E{Key: "nome_busca", Value: bson.D{{Key: "bsonType", Value: "string"}}})
+	}
+	if search.PhoneticName {
+		properties = append(properties, bson.E{Key: "nome_fonetico", Value: bson.D{{Key: "bsonType", Value: "string"}}})
+	}
+
+	return bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: required},
+		{Key: "properties", Value: properties},
+	}
+}
+
+// bsonTypes retorna um tipo único como texto e vários como lista, como no $jsonSchema
+func bsonTypes(types []string) interface{} {
+	if len(types) == 1 {
+		return types[0]
+	}
+	list := make(bson.A, len(types))
+	for i, t := range types {
+		list[i] = t
+	}
+	return list
+}
+
+// joinInts junta as posições das colunas separadas por vírgula
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"testing"
+
+	"MysqlToMongo/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Nomes dos tipos BSON usados no $jsonSchema
+var schemaTypeNames = map[bsontype.Type]string{
+	bsontype.String:     "string",
+	bsontype.Int32:      "int",
+	bsontype.Int64:      "long",
+	bsontype.Double:     "double",
+	bsontype.Decimal128: "decimal",
+	bsontype.DateTime:   "date",
+	bsontype.Null:       "null",
+	bsontype.Binary:     "binData",
+}
+
+// testMapping mapeia cada campo para uma coluna, na ordem de FieldMappings
+func testMapping() *config.MappingConfig {
+	mapping := &config.MappingConfig{}
+	p := &mapping.Pessoas
+	positions := []*int{&p.CPF, &p.Nome, &p.Nasc, &p.Renda, &p.AffinityScore, &p.AffinityPercent, &p.Sexo, &p.CBO,
+		&p.Mae, &p.Nota, &p.Banco, &p.CPFConjuge, &p.ServPublico, &p.DataObito, &p.Cidade, &p.Endereco, &p.Bairro,
+		&p.CEP, &p.UF, &p.DataAtualizacao}
+	for i, position := range positions {
+		*position = i + 1
+	}
+	p.Contatos.Telefones = []int{21, 22}
+	p.Contatos.Emails = []int{23}
+	return mapping
+}
+
+func TestJSONSchemaAcceptsConvertedDocuments(t *testing.T) {
+	mapping := testMapping()
+	schema := JSONSchema(mapping, config.SearchConfig{})
+	properties := schema.Map()["properties"].(bson.D).Map()
+
+	rows := map[string][]interface{}{
+		// Colunas de texto, como no protocolo textual do MySQL
+		"texto": {[]byte("12345678901"), []byte("Maria"), []byte("19800501"), []byte("1500.50"), []byte("0.75"), []byte("75"),
+			[]byte("F"), []byte("2521"), []byte("Ana"), []byte("A"), []byte("001"), []byte("98765432100"), []byte("1"),
+			nil, []byte("Belo Horizonte"), []byte("Rua A"), []byte("Centro"), []byte("30110-000"), []byte("MG"),
+			[]byte("2024-01-02 03:04:05"), []byte("31996320718"), nil, []byte("maria@email.com")},
+		// Colunas numéricas entregues pelo protocolo binário como int64 e float64
+		"números": {int64(12345678901), []byte("João"), nil, float64(1500.5), float64(0.75), int64(75),
+			[]byte("M"), int64(2521), nil, float64(9.5), int64(1), int64(98765432100), int64(1),
+			nil, []byte("Recife"), nil, float64(3), []byte(""), []byte(""),
+			nil, nil, nil, nil},
+		"nulos": make([]interface{}, 23),
+	}
+
+	for name, row := range rows {
+		t.Run(name, func(t *testing.T) {
+			doc, err := BuildDocument(row, mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc.ID = DocumentID("pessoas", 1)
+			data, err := bson.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			elements, err := bson.Raw(data).Elements()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, element := range elements {
+				property, ok := properties[element.Key()].(bson.D)
+				if !ok {
+					t.Errorf("campo %s não está no validador", element.Key())
+					continue
+				}
+				allowed, ok := property.Map()["bsonType"]
+				if !ok || element.Key() == "contatos" {
+					continue
+				}
+				got := schemaTypeNames[element.Value().Type]
+				if !containsType(allowed, got) {
+					t.Errorf("campo %s gravado como %s (%s), o validador aceita %v", element.Key(), got, element.Value(), allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchemaSearchKeys(t *testing.T) {
+	properties := JSONSchema(testMapping(), config.SearchConfig{NormalizedName: true}).Map()["properties"].(bson.D).Map()
+	if _, ok := properties["nome_busca"]; !ok {
+		t.Error("nome_busca deveria estar no validador com search.normalized_name")
+	}
+	if _, ok := properties["nome_fonetico"]; ok {
+		t.Error("nome_fonetico não deveria estar no validador sem search.phonetic_name")
+	}
+}
+
+// containsType verifica se o tipo está entre os aceitos, informados como texto ou lista
+func containsType(allowed interface{}, name string) bool {
+	switch a := allowed.(type) {
+	case string:
+		return a == name
+	case bson.A:
+		for _, t := range a {
+			if t == name {
+				return true
+			}
+		}
+	}
+	return false
+}
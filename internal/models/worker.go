@@ -2,37 +2,120 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"runtime"
+	"time"
 
-	"MysqlToMongo/internal/converter"
+	"MysqlToMongo/internal/deadletter"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/metrics"
+	"MysqlToMongo/internal/retry"
+	"MysqlToMongo/internal/rules"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// Função para calcular o limite de memória
-func calculateMemoryLimit() int64 {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	totalMemory := m.Sys
-	memoryLimit := int64(float64(totalMemory) * 0.7)
-	return memoryLimit
+// Intervalo máximo que um lote incompleto fica parado no gravador antes de ser inserido
+const flushInterval = time.Second
+
+// read pega chunks da fila e envia suas linhas para a conversão até que não haja mais trabalho
+func (p *Pipeline) read(ctx context.Context, id int) error {
+	metrics.SetWorkerState(metrics.StageReader, "", metrics.StateIdle)
+	defer metrics.SetWorkerState(metrics.StageReader, metrics.StateIdle, "")
+
+	for {
+		chunk, ok := p.Chunks.Next(ctx, id)
+		if !ok {
+			return nil
+		}
+
+		log := logging.With("worker_id", id, "chunk", chunk.ID)
+		log.Debugf("Leitor %d: chunk %d (chaves %d a %d)", id, chunk.ID, chunk.Start, chunk.End)
+		start := time.Now()
+		metrics.SetWorkerState(metrics.StageReader, metrics.StateIdle, metrics.StateReading)
+		err := p.readChunk(ctx, log, p.source(id), chunk)
+		metrics.SetWorkerState(metrics.StageReader, metrics.StateReading, metrics.StateIdle)
+		interrupted := ctx.Err() != nil
+		attempt := p.Chunks.Finish(chunk, err, interrupted)
+		if interrupted {
+			return nil
+		}
+		if retry.IsPermanent(err) {
+			return fmt.Errorf("erro no leitor %d: %v", id, err)
+		}
+		if err != nil {
+			log.With("attempt", attempt).Errorf("Erro no leitor %d ao ler o chunk %d (tentativa %d): %v", id, chunk.ID, attempt, err)
+			continue
+		}
+		log.With("duration_ms", time.Since(start).Milliseconds()).Debugf("Leitor %d: chunk %d lido", id, chunk.ID)
+	}
 }
 
-// ProcessBatch processa um lote de registros
-func (w *MigrationWorker) ProcessBatch(ctx context.Context) error {
-	collection := w.MongoClient.Database(w.Config.MongoDB.Database).Collection(w.Config.MongoDB.Collection)
+// readChunk lê o chunk a partir do último registro lido, reabrindo o cursor após falhas transitórias
+func (p *Pipeline) readChunk(ctx context.Context, log *logging.Logger, source Querier, chunk *Chunk) error {
+	attempt := 0
+	for {
+		from := p.Chunks.NextKey(chunk)
+		if from > chunk.End {
+			return nil
+		}
+
+		read, err := p.readRows(ctx, source, chunk, from)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if retry.IsPermanent(err) {
+			return err
+		}
+
+		// A conexão do snapshot não pode ser reaberta no mesmo ponto no tempo
+		if len(p.SnapshotConns) > 0 && retry.IsRetryable(err) {
+			return retry.Permanent(fmt.Errorf("conexão do snapshot perdida, a leitura não pode continuar no mesmo ponto no tempo: %v", err))
+		}
+
+		// Só conta como nova tentativa se o cursor não avançou desde a última falha
+		if read > 0 {
+			attempt = 0
+		}
+		attempt++
+		if !retry.IsRetryable(err) || attempt >= p.Retry.MaxAttempts {
+			return fmt.Errorf("erro na leitura do MySQL: %v", err)
+		}
+
+		log.With("attempt", attempt).Warnf("falha transitória na leitura do chunk %d (tentativa %d/%d), reabrindo cursor na chave %d: %v",
+			chunk.ID, attempt, p.Retry.MaxAttempts, p.Chunks.NextKey(chunk), err)
+		if err := p.Retry.Wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
 
-	// Query para obter apenas os registros do worker usando LIMIT e OFFSET
-	query := fmt.Sprintf("SELECT * FROM %s LIMIT ? OFFSET ?", w.Config.MySQL.Table)
-	rows, err := w.MySQLDB.Query(query, w.EndID-w.StartID+1, w.StartID-1)
+// readRows abre o cursor na chave informada e envia as linhas até o fim do chunk para a conversão,
+// em ordem de chave. Retorna quantas linhas foram enviadas
+func (p *Pipeline) readRows(ctx context.Context, source Querier, chunk *Chunk, from int64) (int64, error) {
+	// A leitura pelo índice da chave não depende da ordem física da tabela nem percorre as linhas anteriores
+	query := fmt.Sprintf("SELECT * FROM %s WHERE `%s` BETWEEN ? AND ? ORDER BY `%s`", p.Config.MySQL.Table, p.Key, p.Key)
+	start := time.Now()
+	rows, err := source.QueryContext(ctx, query, from, chunk.End)
 	if err != nil {
-		return fmt.Errorf("erro na consulta MySQL: %v", err)
+		return 0, err
 	}
+	metrics.MySQLQueryDuration.Observe(time.Since(start).Seconds())
 	defer rows.Close()
 
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
-		return fmt.Errorf("erro ao obter colunas: %v", err)
+		return 0, err
+	}
+	keyIndex, err := KeyIndex(columns, p.Key)
+	if err != nil {
+		return 0, retry.Permanent(err)
 	}
 
 	// Prepare slice for values
@@ -42,103 +125,245 @@ func (w *MigrationWorker) ProcessBatch(ctx context.Context) error {
 		valuePtrs[i] = &values[i]
 	}
 
-	// Calcula o tamanho do lote baseado na memória disponível
-	memoryLimit := calculateMemoryLimit()
-	estimatedDocSize := int64(1024)                        // Estimativa de 1KB por documento
-	batchSize := int(memoryLimit / (estimatedDocSize * 5)) // Divide por 5 workers
-	if batchSize > w.Config.General.BatchSize {
-		batchSize = w.Config.General.BatchSize
+	var read int64
+	for rows.Next() {
+		// Respeita o limite de velocidade para não sobrecarregar o MySQL de origem
+		if err := p.Throttle.WaitRows(ctx, 1); err != nil {
+			return read, err
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return read, err
+		}
+		key, err := KeyValue(values[keyIndex])
+		if err != nil {
+			return read, retry.Permanent(fmt.Errorf("chave '%s' inválida: %v", p.Key, err))
+		}
+
+		// Copia a linha, já que values é reutilizado a cada Scan
+		item := rowItem{
+			chunk:   chunk,
+			key:     key,
+			columns: columns,
+			row:     append([]interface{}(nil), values...),
+		}
+		select {
+		case p.rows <- item:
+		case <-ctx.Done():
+			return read, ctx.Err()
+		}
+
+		// Só conta a linha depois que ela entrou no pipeline
+		p.Chunks.AddRead(chunk, key)
+		metrics.RowsRead.Inc()
+		read++
 	}
 
-	batch := make([]interface{}, 0, batchSize)
-	count := 0
+	return read, rows.Err()
+}
+
+// convert transforma as linhas em documentos, aplica as regras e envia o resultado para a gravação
+func (p *Pipeline) convert(ctx context.Context, id int) error {
+	for item := range p.rows {
+		pending := pendingDocument{chunk: item.chunk, columns: item.columns, row: item.row}
 
-	for rows.Next() {
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return fmt.Errorf("erro ao escanear linha: %v", err)
-		}
-
-		// Create document for MongoDB
-		doc := OrderedDocument{}
-
-		// Map pessoa fields
-		p := w.Config.Mapping.Pessoas
-		// Convert CPF to string and pad with leading zeros
-		cpfStr := converter.ConvertBinaryToString(values[p.CPF-1])
-		if cpfStr != nil {
-			if str, ok := cpfStr.(string); ok {
-				// Pad with leading zeros to ensure 11 digits
-				doc.CPF = fmt.Sprintf("%011s", str)
-			} else {
-				doc.CPF = cpfStr
-			}
-		} else {
-			doc.CPF = nil
-		}
-		doc.Nome = converter.ConvertBinaryToString(values[p.Nome-1])
-		doc.Nasc = converter.ConvertToDatePtr(values[p.Nasc-1])
-		doc.Renda = converter.ConvertToDecimal(values[p.Renda-1])
-		doc.AffinityScore = converter.ConvertToDecimal(values[p.AffinityScore-1])
-		doc.AffinityPercent = converter.ConvertToDecimal(values[p.AffinityPercent-1])
-		doc.Sexo = converter.ConvertBinaryToString(values[p.Sexo-1])
-		doc.CBO = converter.ConvertBinaryToString(values[p.CBO-1])
-		doc.Mae = converter.ConvertBinaryToString(values[p.Mae-1])
-		doc.Nota = converter.ConvertBinaryToString(values[p.Nota-1])
-		doc.Banco = converter.ConvertBinaryToString(values[p.Banco-1])
-		doc.CPFConjuge = converter.ConvertOptionalField(values[p.CPFConjuge-1])
-		doc.ServPublico = converter.ConvertOptionalField(values[p.ServPublico-1])
-		doc.DataObito = converter.ConvertToDatePtr(values[p.DataObito-1])
-		doc.Cidade = converter.ConvertBinaryToString(values[p.Cidade-1])
-		doc.Endereco = converter.ConvertBinaryToString(values[p.Endereco-1])
-		doc.Bairro = converter.ConvertOptionalField(values[p.Bairro-1])
-		doc.CEP = converter.ConvertBinaryToString(values[p.CEP-1])
-		doc.UF = converter.ConvertBinaryToString(values[p.UF-1])
-		doc.DataAtualizacao = converter.ConvertToTimePtr(values[p.DataAtualizacao-1])
-
-		// Map telefones
-		telefones := make([]interface{}, 0)
-		for _, pos := range p.Contatos.Telefones {
-			if values[pos-1] != nil {
-				telefone := converter.ConvertBinaryToString(values[pos-1])
-				if str, ok := telefone.(string); ok && str != "" {
-					telefones = append(telefones, str)
-				}
+		doc, err := BuildDocument(item.row, p.Config.Mapping)
+		if err != nil && p.DryRun != nil {
+			p.DryRun.ObserveFailure(err)
+			p.settle(item.chunk, 1)
+			continue
+		}
+		if err != nil {
+			if err := p.writeDeadLetter(ctx, id, deadletter.StageConversion, err, pending); err != nil {
+				return err
 			}
+			p.settle(item.chunk, 1)
+			continue
 		}
-		doc.Contatos.Telefones = telefones
+		p.countConversionFailures(doc, item.row)
+		doc.ID = DocumentID(p.Config.MySQL.Table, item.key)
+		doc.SetSearchKeys(p.Config.Search)
+		pending.doc = doc
 
-		// Map emails
-		emails := make([]interface{}, 0)
-		for _, pos := range p.Contatos.Emails {
-			if values[pos-1] != nil {
-				email := converter.ConvertBinaryToString(values[pos-1])
-				if str, ok := email.(string); ok && str != "" {
-					emails = append(emails, str)
-				}
-			}
+		// Mede o documento para o cálculo automático do tamanho do lote
+		p.BatchSizer.Observe(doc)
+		if p.DryRun != nil {
+			p.DryRun.ObserveDocument(doc, item.row)
 		}
-		doc.Contatos.Emails = emails
 
-		batch = append(batch, doc)
-		count++
+		// Aplica as regras de filtro e roteamento
+		decision := p.Rules.Evaluate(doc)
+		switch decision.Action {
+		case rules.ActionSkip:
+			p.settle(item.chunk, 1)
+			continue
+		case rules.ActionRoute:
+			pending.target = decision.Collection
+		}
 
-		// Insert batch when it reaches the batch size
-		if len(batch) >= batchSize {
-			if _, err := collection.InsertMany(ctx, batch); err != nil {
-				return fmt.Errorf("erro ao inserir lote: %v", err)
+		p.docs <- pending
+	}
+	return nil
+}
+
+// countConversionFailures conta, por campo, os valores da origem que o conversor não conseguiu converter
+func (p *Pipeline) countConversionFailures(doc *OrderedDocument, row []interface{}) {
+	for _, m := range p.mappings {
+		if value, _ := doc.Field(m.Field); isEmptyValue(value) && conversionFailed(m, row) {
+			metrics.ConversionFailures.WithLabelValues(m.Field).Inc()
+		}
+	}
+}
+
+// write agrupa os documentos por collection de destino e os insere em lotes
+func (p *Pipeline) write(ctx context.Context, id int) error {
+	metrics.SetWorkerState(metrics.StageWriter, "", metrics.StateIdle)
+	defer metrics.SetWorkerState(metrics.StageWriter, metrics.StateIdle, "")
+
+	batches := make(map[string][]pendingDocument)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case pending, ok := <-p.docs:
+			if !ok {
+				return p.flushAll(ctx, id, batches)
+			}
+			batches[pending.target] = append(batches[pending.target], pending)
+
+			// Insert batch when it reaches the batch size
+			if len(batches[pending.target]) >= p.BatchSizer.Size() {
+				if err := p.flush(ctx, id, batches, pending.target); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			// Grava lotes incompletos para que os chunks não fiquem esperando
+			if err := p.flushAll(ctx, id, batches); err != nil {
+				return err
 			}
-			w.ProgressChan <- len(batch)
-			batch = batch[:0]
 		}
 	}
+}
+
+// flushAll insere todos os lotes pendentes do gravador
+func (p *Pipeline) flushAll(ctx context.Context, id int, batches map[string][]pendingDocument) error {
+	for target := range batches {
+		if err := p.flush(ctx, id, batches, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush insere o lote pendente de uma collection de destino
+func (p *Pipeline) flush(ctx context.Context, id int, batches map[string][]pendingDocument, target string) error {
+	batch := batches[target]
+	if len(batch) == 0 {
+		return nil
+	}
 
-	// Insert remaining documents
-	if len(batch) > 0 {
-		if _, err := collection.InsertMany(ctx, batch); err != nil {
-			return fmt.Errorf("erro ao inserir lote final: %v", err)
+	// No dry-run o lote é apenas descartado
+	if p.DryRun == nil {
+		name := target
+		if name == "" {
+			name = p.Config.MongoDB.Collection
+		}
+		collection := p.MongoClient.Database(p.Config.MongoDB.Database).Collection(name)
+		if err := p.Throttle.WaitBatch(ctx); err != nil {
+			return err
+		}
+		start := time.Now()
+		metrics.SetWorkerState(metrics.StageWriter, metrics.StateIdle, metrics.StateInserting)
+		failed, err := p.insertMany(ctx, id, collection, batch, target == "")
+		metrics.SetWorkerState(metrics.StageWriter, metrics.StateInserting, metrics.StateIdle)
+		if err != nil {
+			return fmt.Errorf("erro ao inserir lote na collection '%s': %v", name, err)
 		}
-		w.ProgressChan <- len(batch)
+		metrics.BatchInsertDuration.Observe(time.Since(start).Seconds())
+		metrics.DocumentsWritten.WithLabelValues(name).Add(float64(len(batch) - failed))
+		logging.With("worker_id", id, "collection", name, "batch_size", len(batch), "duration_ms", time.Since(start).Milliseconds()).
+			Debugf("Gravador %d: %d documentos inseridos na collection '%s'", id, len(batch), name)
+	}
+
+	// Contabiliza os registros gravados de cada chunk
+	settled := make(map[*Chunk]int64)
+	for _, pending := range batch {
+		settled[pending.chunk]++
+	}
+	for chunk, n := range settled {
+		p.Chunks.Settle(chunk, n)
 	}
+	p.ProgressChan <- len(batch)
 
+	batches[target] = batch[:0]
 	return nil
 }
+
+// settle contabiliza registros descartados pelas regras ou enviados ao dead-letter na conversão
+func (p *Pipeline) settle(chunk *Chunk, n int64) {
+	p.Chunks.Settle(chunk, n)
+	p.ProgressChan <- int(n)
+}
+
+// insertMany insere um lote sem ordem, isolando as falhas por documento.
+// Duplicados de CPF são resolvidos se configurado e as demais falhas vão para o dead-letter; retorna quantas foram
+func (p *Pipeline) insertMany(ctx context.Context, id int, collection *mongo.Collection, batch []pendingDocument, resolveDuplicates bool) (int, error) {
+	docs := make([]interface{}, len(batch))
+	for i, pending := range batch {
+		docs[i] = pending.doc
+	}
+
+	err := p.Retry.Do(ctx, fmt.Sprintf("inserção do gravador %d", id), func() error {
+		_, err := collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		return err
+	})
+	if err == nil {
+		return 0, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return 0, err
+	}
+
+	failed := 0
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		// O _id é derivado da chave primária do registro, então um _id repetido indica que uma tentativa anterior já gravou o documento
+		if duplicates.IsDuplicateID(writeErr.WriteError) {
+			continue
+		}
+
+		pending := batch[writeErr.Index]
+		failure := error(writeErr)
+
+		if resolveDuplicates && p.Duplicates != nil && duplicates.IsDuplicateKey(writeErr.WriteError) {
+			if failure = p.Duplicates.Resolve(ctx, collection, pending.doc); failure == nil {
+				continue
+			}
+		}
+
+		if err := p.writeDeadLetter(ctx, id, deadletter.StageInsert, failure, pending); err != nil {
+			return failed, err
+		}
+		failed++
+	}
+	return failed, nil
+}
+
+// writeDeadLetter grava um registro com falha no dead-letter
+func (p *Pipeline) writeDeadLetter(ctx context.Context, id int, stage string, failure error, pending pendingDocument) error {
+	entry := deadletter.Entry{
+		WorkerID:  id,
+		Stage:     stage,
+		Error:     failure.Error(),
+		SourceRow: deadletter.SourceRow(pending.columns, pending.row),
+	}
+	if pending.doc != nil {
+		entry.Document = pending.doc
+	}
+	metrics.DeadLetters.WithLabelValues(stage).Inc()
+	return p.DeadLetter.Write(ctx, entry)
+}
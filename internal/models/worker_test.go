@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/deadletter"
+	"MysqlToMongo/internal/duplicates"
+	"MysqlToMongo/internal/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// Com um índice único de cpf sem partial_filter, o segundo documento sem CPF é rejeitado como duplicado.
+// Nenhuma estratégia pode compará-lo com outro documento sem CPF: ele vai para o dead-letter
+func TestInsertManyNullCPFIsNotResolved(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	strategies := []string{
+		duplicates.StrategyKeepFirst,
+		duplicates.StrategyKeepNewest,
+		duplicates.StrategyMergeContacts,
+		duplicates.StrategyMoveToCollection,
+	}
+
+	for _, strategy := range strategies {
+		mt.Run(strategy, func(mt *mtest.T) {
+			resolver, err := duplicates.New(config.DuplicatesConfig{Strategy: strategy, Collection: "pessoas_duplicates"}, mt.DB, mt.Coll.Name())
+			if err != nil {
+				mt.Fatal(err)
+			}
+			path := filepath.Join(mt.TempDir(), "dead_letter.jsonl")
+			sink, err := deadletter.NewFileSink(path)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			defer sink.Close()
+			p := &Pipeline{Duplicates: resolver, DeadLetter: sink, Retry: retry.Policy{MaxAttempts: 1}}
+
+			batch := []pendingDocument{
+				{columns: []string{"cpf"}, row: []interface{}{nil}, doc: &OrderedDocument{ID: DocumentID("pessoas", 1)}},
+				{columns: []string{"cpf"}, row: []interface{}{nil}, doc: &OrderedDocument{ID: DocumentID("pessoas", 2)}},
+			}
+			mt.AddMockResponses(bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "n", Value: 1},
+				{Key: "writeErrors", Value: bson.A{bson.D{
+					{Key: "index", Value: 1},
+					{Key: "code", Value: 11000},
+					{Key: "errmsg", Value: "E11000 duplicate key error collection: db.pessoas index: cpf_1 dup key: { cpf: null }"},
+					{Key: "keyPattern", Value: bson.D{{Key: "cpf", Value: 1}}},
+					{Key: "keyValue", Value: bson.D{{Key: "cpf", Value: nil}}},
+				}}},
+			})
+
+			failed, err := p.insertMany(context.Background(), 1, mt.Coll, batch, true)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if failed != 1 {
+				mt.Errorf("insertMany() = %d falhas, esperada 1", failed)
+			}
+
+			// Apenas o insert foi enviado: nada foi buscado, substituído, mesclado ou arquivado
+			for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+				if event.CommandName != "insert" || event.Command.Lookup("insert").StringValue() != mt.Coll.Name() {
+					mt.Errorf("comando inesperado %s: %s", event.CommandName, event.Command)
+				}
+			}
+			if stats := resolver.Stats(); stats != (duplicates.Stats{}) {
+				mt.Errorf("Stats() = %+v, esperado nenhum duplicado", stats)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if !strings.Contains(string(data), "sem CPF") || strings.Count(string(data), "\n") != 1 {
+				mt.Errorf("dead-letter = %s, esperado um registro sem CPF", data)
+			}
+		})
+	}
+}
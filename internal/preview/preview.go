@@ -0,0 +1,198 @@
+package preview
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Número de registros mostrados quando --limit não é informado
+const defaultLimit = 10
+
+// Options representa a seleção dos registros a serem mostrados
+type Options struct {
+	ID    string // Valor da chave primária
+	Where string // Condição SQL aplicada à tabela de origem
+	Limit int
+}
+
+// Run busca os registros selecionados e mostra, para cada campo, os valores de origem,
+// o conversor usado e o valor convertido, seguidos do documento BSON em Extended JSON
+func Run(ctx context.Context, w io.Writer, cfg *config.Config, db *sql.DB, opts Options) error {
+	query, args, err := buildQuery(ctx, cfg, db, opts)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("erro ao consultar o MySQL: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	mappings := models.FieldMappings(cfg.Mapping)
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return err
+		}
+		count++
+		fmt.Fprintf(w, "=== Registro %d ===\n", count)
+		printRow(w, columns, values, mappings, cfg)
+		fmt.Fprintln(w)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if count == 0 {
+		fmt.Fprintln(w, "Nenhum registro encontrado")
+	}
+	return nil
+}
+
+// buildQuery monta a consulta conforme a seleção informada
+func buildQuery(ctx context.Context, cfg *config.Config, db *sql.DB, opts Options) (string, []interface{}, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	query := fmt.Sprintf("SELECT * FROM %s", cfg.MySQL.Table)
+
+	switch {
+	case opts.ID != "" && opts.Where != "":
+		return "", nil, fmt.Errorf("informe a chave primária ou a condição WHERE, não ambas")
+	case opts.ID != "":
+		key, err := primaryKey(ctx, db, cfg.MySQL.Database, cfg.MySQL.Table)
+		if err != nil {
+			return "", nil, err
+		}
+		return query + fmt.Sprintf(" WHERE `%s` = ? LIMIT ?", key), []interface{}{opts.ID, limit}, nil
+	case opts.Where != "":
+		return query + " WHERE " + opts.Where + " LIMIT ?", []interface{}{limit}, nil
+	}
+	return query + " LIMIT ?", []interface{}{limit}, nil
+}
+
+// primaryKey retorna a coluna da chave primária da tabela
+func primaryKey(ctx context.Context, db *sql.DB, database, table string) (string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION`, database, table)
+	if err != nil {
+		return "", fmt.Errorf("erro ao consultar a chave primária: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return "", err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	switch len(keys) {
+	case 0:
+		return "", fmt.Errorf("a tabela '%s' não tem chave primária; use --where", table)
+	case 1:
+		return keys[0], nil
+	}
+	return "", fmt.Errorf("a chave primária da tabela '%s' é composta (%s); use --where", table, strings.Join(keys, ", "))
+}
+
+// printRow mostra a tabela de campos de um registro e o documento resultante
+func printRow(w io.Writer, columns []string, values []interface{}, mappings []models.FieldMapping, cfg *config.Config) {
+	doc, err := models.BuildDocument(values, cfg.Mapping)
+	if err != nil {
+		fmt.Fprintf(w, "Erro na conversão: %v\n", err)
+	} else {
+		doc.SetSearchKeys(cfg.Search)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CAMPO\tCONVERSOR\tCOLUNA\tVALOR DE ORIGEM\tVALOR CONVERTIDO")
+	for _, m := range mappings {
+		converted := "-"
+		if doc != nil {
+			value, _ := doc.Field(m.Field)
+			converted = formatValue(value)
+		}
+		for i, position := range m.Columns {
+			source, column := "<fora da linha>", fmt.Sprintf("#%d", position)
+			if position >= 1 && position <= len(values) {
+				source = formatValue(values[position-1])
+				column = fmt.Sprintf("#%d %s", position, columns[position-1])
+			}
+			// Em campos de várias colunas o valor convertido aparece apenas na primeira
+			if i > 0 {
+				fmt.Fprintf(tw, "\t\t%s\t%s\t\n", column, source)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Field, m.Converter, column, source, converted)
+		}
+	}
+	tw.Flush()
+
+	if doc == nil {
+		return
+	}
+	data, err := bson.MarshalExtJSONIndent(doc, false, false, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "Erro ao serializar documento: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, "Documento BSON:")
+	fmt.Fprintln(w, string(data))
+}
+
+// formatValue formata um valor de origem ou convertido para exibição, mostrando o tipo quando não é texto
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case []byte:
+		return fmt.Sprintf("%q", string(v))
+	case string:
+		return fmt.Sprintf("%q", v)
+	case *time.Time:
+		if v == nil {
+			return "null"
+		}
+		return fmt.Sprintf("Date(%s)", v.Format(time.RFC3339))
+	case time.Time:
+		return fmt.Sprintf("Date(%s)", v.Format(time.RFC3339))
+	case primitive.Decimal128:
+		return fmt.Sprintf("Decimal128(%s)", v.String())
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprintf("%v (%T)", value, value)
+}
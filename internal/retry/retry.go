@@ -0,0 +1,187 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/logging"
+
+	"github.com/go-sql-driver/mysql"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Valores padrão usados quando a política não é configurada
+const (
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultJitter         = 0.2
+)
+
+// Códigos de erro do servidor MySQL que indicam falha transitória. A perda da conexão (2006, 2013)
+// é um erro do cliente, que o driver informa como mysql.ErrInvalidConn ou driver.ErrBadConn
+var mysqlRetryableCodes = map[uint16]bool{
+	1040: true, // Too many connections
+	1205: true, // Lock wait timeout exceeded
+	1213: true, // Deadlock found when trying to get lock
+}
+
+// Códigos de erro do MongoDB que indicam falha transitória (troca de primário, desligamento, rede)
+var mongoRetryableCodes = map[int]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	262:   true, // ExceededTimeLimit
+	9001:  true, // SocketException
+	10107: true, // NotWritablePrimary
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotPrimaryNoSecondaryOk
+	13436: true, // NotPrimaryOrSecondary
+}
+
+// Policy representa a política de novas tentativas com backoff exponencial
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64
+}
+
+// NewPolicy cria a política a partir da configuração, aplicando os valores padrão
+func NewPolicy(cfg config.RetryConfig) Policy {
+	policy := Policy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.MaxBackoffMs) * time.Millisecond,
+		Jitter:         cfg.Jitter,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultMaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+	if policy.Jitter <= 0 || policy.Jitter > 1 {
+		policy.Jitter = defaultJitter
+	}
+	return policy
+}
+
+// Backoff calcula a espera antes da tentativa seguinte à tentativa informada (a partir de 1)
+func (p Policy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	// Aplica o jitter para que os workers não tentem todos ao mesmo tempo
+	delta := (rand.Float64()*2 - 1) * p.Jitter * float64(backoff)
+	return backoff + time.Duration(delta)
+}
+
+// Wait aguarda o backoff da tentativa ou o cancelamento do contexto
+func (p Policy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Do executa a operação, repetindo-a enquanto o erro for transitório
+func (p Policy) Do(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsRetryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		logging.Warnf("falha transitória em %s (tentativa %d/%d): %v", operation, attempt, p.MaxAttempts, err)
+		if err := p.Wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// permanentError marca um erro que não deve ser repetido
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marca o erro como fatal, mesmo que sua causa seja transitória
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent verifica se o erro foi marcado como fatal com Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// IsRetryable classifica o erro como transitório (rede, troca de primário, lock, deadlock) ou fatal
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if IsPermanent(err) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// Erros do MySQL
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlRetryableCodes[mysqlErr.Number]
+	}
+	if errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Erros do MongoDB
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		if serverErr.HasErrorLabel("RetryableWriteError") || serverErr.HasErrorLabel("TransientTransactionError") {
+			return true
+		}
+		for code := range mongoRetryableCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Erros de rede genéricos
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"conexão perdida", mysql.ErrInvalidConn, true},
+		{"conexão ruim no pool", driver.ErrBadConn, true},
+		{"conexão perdida encapsulada", fmt.Errorf("leitura: %w", mysql.ErrInvalidConn), true},
+		{"fim inesperado", io.ErrUnexpectedEOF, true},
+		{"deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205}, true},
+		{"too many connections", &mysql.MySQLError{Number: 1040}, true},
+		{"tabela inexistente", &mysql.MySQLError{Number: 1146}, false},
+		{"permanente", Permanent(mysql.ErrInvalidConn), false},
+		{"cancelado", context.Canceled, false},
+		{"erro genérico", errors.New("falha"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable(%v) = %v, esperado %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
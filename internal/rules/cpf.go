@@ -0,0 +1,42 @@
+package rules
+
+// ValidCPF verifica o tamanho e os dígitos verificadores de um CPF
+func ValidCPF(cpf string) bool {
+	if len(cpf) != 11 {
+		return false
+	}
+
+	digits := make([]int, 11)
+	allEqual := true
+	for i, c := range cpf {
+		if c < '0' || c > '9' {
+			return false
+		}
+		digits[i] = int(c - '0')
+		if digits[i] != digits[0] {
+			allEqual = false
+		}
+	}
+
+	// CPFs com todos os dígitos iguais passam no cálculo, mas são inválidos
+	if allEqual {
+		return false
+	}
+
+	// Calcula os dois dígitos verificadores
+	for check := 9; check <= 10; check++ {
+		sum := 0
+		for i := 0; i < check; i++ {
+			sum += digits[i] * (check + 1 - i)
+		}
+		digit := sum * 10 % 11
+		if digit == 10 {
+			digit = 0
+		}
+		if digit != digits[check] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,199 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"MysqlToMongo/internal/config"
+)
+
+// Ações possíveis para um documento após a avaliação das regras
+const (
+	ActionInsert = "insert"
+	ActionSkip   = "skip"
+	ActionRoute  = "route"
+)
+
+// Operadores suportados pelas regras
+const (
+	OpExists     = "exists"
+	OpMissing    = "missing"
+	OpEquals     = "equals"
+	OpNotEquals  = "not_equals"
+	OpRegex      = "regex"
+	OpNotRegex   = "not_regex"
+	OpInvalidCPF = "invalid_cpf"
+)
+
+// Document representa qualquer documento que permita acessar campos pelo nome
+type Document interface {
+	Field(name string) (any, bool)
+}
+
+// Decision representa o destino de um documento após a avaliação das regras
+type Decision struct {
+	Action     string
+	Collection string
+	Rule       string
+}
+
+// RuleCount representa o total de registros afetados por uma regra
+type RuleCount struct {
+	Name       string
+	Action     string
+	Collection string
+	Count      int64
+}
+
+// rule representa uma regra já validada e compilada
+type rule struct {
+	config.RuleConfig
+	pattern *regexp.Regexp
+	count   atomic.Int64
+}
+
+// Engine avalia as regras configuradas sobre os documentos convertidos
+type Engine struct {
+	rules []*rule
+}
+
+// New valida e compila as regras da configuração. O campo de cada regra precisa existir em fields,
+// já que Evaluate ignora as regras cujo campo o documento não tem
+func New(configs []config.RuleConfig, fields Document) (*Engine, error) {
+	engine := &Engine{}
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("regra_%d", i+1)
+		}
+		r := &rule{RuleConfig: cfg}
+
+		if _, ok := fields.Field(cfg.Field); !ok {
+			return nil, fmt.Errorf("regra '%s': campo desconhecido '%s'", cfg.Name, cfg.Field)
+		}
+
+		switch cfg.Operator {
+		case OpExists, OpMissing, OpEquals, OpNotEquals, OpInvalidCPF:
+		case OpRegex, OpNotRegex:
+			pattern, err := regexp.Compile(cfg.Value)
+			if err != nil {
+				return nil, fmt.Errorf("regra '%s': expressão regular inválida: %v", cfg.Name, err)
+			}
+			r.pattern = pattern
+		default:
+			return nil, fmt.Errorf("regra '%s': operador desconhecido '%s'", cfg.Name, cfg.Operator)
+		}
+
+		switch cfg.Action {
+		case ActionSkip:
+		case ActionRoute:
+			if cfg.Collection == "" {
+				return nil, fmt.Errorf("regra '%s': ação 'route' exige collection de destino", cfg.Name)
+			}
+		default:
+			return nil, fmt.Errorf("regra '%s': ação desconhecida '%s'", cfg.Name, cfg.Action)
+		}
+
+		engine.rules = append(engine.rules, r)
+	}
+	return engine, nil
+}
+
+// Evaluate aplica as regras em ordem e retorna a decisão da primeira que casar
+func (e *Engine) Evaluate(doc Document) Decision {
+	if e == nil {
+		return Decision{Action: ActionInsert}
+	}
+	for _, r := range e.rules {
+		value, ok := doc.Field(r.Field)
+		if !ok {
+			continue
+		}
+		if r.matches(value) {
+			r.count.Add(1)
+			return Decision{Action: r.Action, Collection: r.Collection, Rule: r.Name}
+		}
+	}
+	return Decision{Action: ActionInsert}
+}
+
+// Collections retorna as collections de destino usadas pelas regras de roteamento
+func (e *Engine) Collections() []string {
+	if e == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var collections []string
+	for _, r := range e.rules {
+		if r.Action == ActionRoute && !seen[r.Collection] {
+			seen[r.Collection] = true
+			collections = append(collections, r.Collection)
+		}
+	}
+	return collections
+}
+
+// Summary retorna os contadores de cada regra
+func (e *Engine) Summary() []RuleCount {
+	if e == nil {
+		return nil
+	}
+	summary := make([]RuleCount, 0, len(e.rules))
+	for _, r := range e.rules {
+		summary = append(summary, RuleCount{
+			Name:       r.Name,
+			Action:     r.Action,
+			Collection: r.Collection,
+			Count:      r.count.Load(),
+		})
+	}
+	return summary
+}
+
+// matches verifica se o valor do campo satisfaz a condição da regra
+func (r *rule) matches(value any) bool {
+	switch r.Operator {
+	case OpExists:
+		return !isEmpty(value)
+	case OpMissing:
+		return isEmpty(value)
+	case OpEquals:
+		return !isEmpty(value) && toString(value) == r.Value
+	case OpNotEquals:
+		return isEmpty(value) || toString(value) != r.Value
+	case OpRegex:
+		return !isEmpty(value) && r.pattern.MatchString(toString(value))
+	case OpNotRegex:
+		return isEmpty(value) || !r.pattern.MatchString(toString(value))
+	case OpInvalidCPF:
+		return isEmpty(value) || !ValidCPF(toString(value))
+	}
+	return false
+}
+
+// isEmpty verifica se o valor convertido deve ser considerado ausente
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case *time.Time:
+		return v == nil
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// toString converte o valor do campo para comparação textual
+func toString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case *time.Time:
+		return v.Format("2006-01-02")
+	}
+	return fmt.Sprint(value)
+}
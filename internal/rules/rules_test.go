@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"MysqlToMongo/internal/config"
+)
+
+// document é um documento de teste com os campos pelo nome
+type document map[string]any
+
+func (d document) Field(name string) (any, bool) {
+	value, ok := d[name]
+	return value, ok
+}
+
+// fields conhece os campos usados nos testes
+var fields = document{"cpf": nil, "nome": nil, "data_obito": nil, "uf": nil, "contatos.emails": nil}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    config.RuleConfig
+		wantErr string
+	}{
+		{"operador desconhecido", config.RuleConfig{Field: "cpf", Operator: "contains", Action: ActionSkip}, "operador desconhecido"},
+		{"expressão regular inválida", config.RuleConfig{Field: "nome", Operator: OpRegex, Value: "([", Action: ActionSkip}, "expressão regular inválida"},
+		{"ação desconhecida", config.RuleConfig{Field: "cpf", Operator: OpExists, Action: "delete"}, "ação desconhecida"},
+		{"route sem collection", config.RuleConfig{Field: "cpf", Operator: OpExists, Action: ActionRoute}, "exige collection"},
+		{"campo desconhecido", config.RuleConfig{Field: "data_obto", Operator: OpExists, Action: ActionSkip}, "campo desconhecido 'data_obto'"},
+		{"campo vazio", config.RuleConfig{Operator: OpExists, Action: ActionSkip}, "campo desconhecido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]config.RuleConfig{tt.rule}, fields)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() erro = %v, esperado %q", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "regra_1") {
+				t.Errorf("New() erro = %v, esperado o nome padrão da regra", err)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	obito := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	engine, err := New([]config.RuleConfig{
+		{Name: "cpf_invalido", Field: "cpf", Operator: OpInvalidCPF, Action: ActionSkip},
+		{Name: "obitos", Field: "data_obito", Operator: OpExists, Action: ActionRoute, Collection: "pessoas_obitos"},
+		{Name: "teste", Field: "nome", Operator: OpRegex, Value: "^TESTE", Action: ActionSkip},
+		{Name: "sp", Field: "uf", Operator: OpEquals, Value: "SP", Action: ActionRoute, Collection: "pessoas_sp"},
+		{Name: "sem_email", Field: "contatos.emails", Operator: OpMissing, Action: ActionRoute, Collection: "pessoas_sem_email"},
+	}, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := "52998224725"
+	tests := []struct {
+		name       string
+		doc        document
+		wantAction string
+		wantTarget string
+		wantRule   string
+	}{
+		{"cpf com dígito errado", document{"cpf": "52998224726"}, ActionSkip, "", "cpf_invalido"},
+		{"cpf com dígitos iguais", document{"cpf": "11111111111"}, ActionSkip, "", "cpf_invalido"},
+		{"cpf nulo", document{"cpf": nil}, ActionSkip, "", "cpf_invalido"},
+		{"óbito", document{"cpf": valid, "data_obito": &obito}, ActionRoute, "pessoas_obitos", "obitos"},
+		{"óbito nulo não casa", document{"cpf": valid, "data_obito": (*time.Time)(nil), "contatos.emails": []any{"a@b.com"}}, ActionInsert, "", ""},
+		{"regex", document{"cpf": valid, "nome": "TESTE DA SILVA"}, ActionSkip, "", "teste"},
+		{"equals", document{"cpf": valid, "nome": "MARIA", "uf": "SP"}, ActionRoute, "pessoas_sp", "sp"},
+		{"lista vazia é ausente", document{"cpf": valid, "uf": "RJ", "contatos.emails": []any{}}, ActionRoute, "pessoas_sem_email", "sem_email"},
+		{"nenhuma regra casa", document{"cpf": valid, "uf": "RJ", "contatos.emails": []any{"a@b.com"}}, ActionInsert, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := engine.Evaluate(tt.doc)
+			if got.Action != tt.wantAction || got.Collection != tt.wantTarget || got.Rule != tt.wantRule {
+				t.Errorf("Evaluate() = %+v, esperado {%s %s %s}", got, tt.wantAction, tt.wantTarget, tt.wantRule)
+			}
+		})
+	}
+
+	if got := engine.Collections(); strings.Join(got, ",") != "pessoas_obitos,pessoas_sp,pessoas_sem_email" {
+		t.Errorf("Collections() = %v", got)
+	}
+	counts := make(map[string]int64)
+	for _, c := range engine.Summary() {
+		counts[c.Name] = c.Count
+	}
+	if counts["cpf_invalido"] != 3 || counts["sp"] != 1 || counts["sem_email"] != 1 {
+		t.Errorf("Summary() = %v", counts)
+	}
+}
+
+func TestNilEngineInsertsEverything(t *testing.T) {
+	var engine *Engine
+	if got := engine.Evaluate(document{"cpf": "1"}); got.Action != ActionInsert {
+		t.Errorf("Evaluate() sem regras = %+v, esperado insert", got)
+	}
+}
+
+func TestValidCPF(t *testing.T) {
+	tests := []struct {
+		cpf  string
+		want bool
+	}{
+		{"52998224725", true},
+		{"12345678909", true},
+		{"52998224724", false},
+		{"00000000000", false},
+		{"5299822472", false},
+		{"529.982.247-25", false},
+		{"5299822472a", false},
+	}
+	for _, tt := range tests {
+		if got := ValidCPF(tt.cpf); got != tt.want {
+			t.Errorf("ValidCPF(%q) = %v, esperado %v", tt.cpf, got, tt.want)
+		}
+	}
+}
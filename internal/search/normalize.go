@@ -0,0 +1,103 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Letras acentuadas e suas equivalentes sem acento, já em maiúsculas
+var accents = map[rune]rune{
+	'Á': 'A', 'À': 'A', 'Â': 'A', 'Ã': 'A', 'Ä': 'A', 'Å': 'A',
+	'É': 'E', 'È': 'E', 'Ê': 'E', 'Ë': 'E',
+	'Í': 'I', 'Ì': 'I', 'Î': 'I', 'Ï': 'I',
+	'Ó': 'O', 'Ò': 'O', 'Ô': 'O', 'Õ': 'O', 'Ö': 'O',
+	'Ú': 'U', 'Ù': 'U', 'Û': 'U', 'Ü': 'U',
+	'Ç': 'C', 'Ñ': 'N', 'Ý': 'Y',
+}
+
+// Normalize converte o nome para maiúsculas, remove acentos e pontuação e junta espaços repetidos.
+// "  João  d'Ávila-Souza " vira "JOAO DAVILA SOUZA"
+func Normalize(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	space := false
+	for _, r := range strings.ToUpper(name) {
+		if plain, ok := accents[r]; ok {
+			r = plain
+		}
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case r == '\'' || r == '´' || r == '`':
+			// Apóstrofos são removidos sem separar as palavras
+		case unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// Substituições da chave fonética, aplicadas em ordem, inspiradas no algoritmo BuscaBR
+var phoneticRules = []struct{ from, to string }{
+	{"BL", "B"}, {"BR", "B"},
+	{"PH", "F"},
+	{"GL", "G"}, {"GR", "G"}, {"MG", "G"}, {"NG", "G"}, {"RG", "G"},
+	{"Y", "I"},
+	{"GE", "J"}, {"GI", "J"}, {"RJ", "J"}, {"MJ", "J"},
+	{"CA", "K"}, {"CO", "K"}, {"CU", "K"}, {"CK", "K"}, {"QU", "K"}, {"Q", "K"},
+	{"LH", "L"}, {"NH", "N"},
+	{"AO", "M"}, {"GM", "M"}, {"MD", "M"}, {"OM", "M"}, {"ON", "M"}, {"N", "M"},
+	{"PR", "P"},
+	{"L", "R"},
+	{"CE", "S"}, {"CI", "S"}, {"CH", "S"}, {"CS", "S"}, {"RS", "S"}, {"TS", "S"}, {"X", "S"}, {"Z", "S"}, {"SH", "S"},
+	{"TR", "T"}, {"TL", "T"}, {"CT", "T"}, {"RT", "T"}, {"ST", "T"}, {"PT", "T"},
+	{"W", "V"},
+	{"C", "K"},
+}
+
+// Phonetic gera uma chave fonética para nomes em português, para que grafias como
+// "Thaís"/"Taiz" ou "Luiz"/"Luis" tenham a mesma chave. Cada palavra é codificada separadamente
+func Phonetic(name string) string {
+	words := strings.Fields(Normalize(name))
+	keys := make([]string, 0, len(words))
+	for _, word := range words {
+		if key := phoneticWord(word); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return strings.Join(keys, " ")
+}
+
+// phoneticWord codifica uma palavra já normalizada
+func phoneticWord(word string) string {
+	// Terminações que não alteram o som
+	for _, suffix := range []string{"S", "Z", "R", "M", "N", "L"} {
+		if len(word) > 2 && strings.HasSuffix(word, suffix) {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+
+	for _, rule := range phoneticRules {
+		word = strings.ReplaceAll(word, rule.from, rule.to)
+	}
+
+	// Remove vogais e H, exceto a primeira letra, e letras repetidas
+	var b strings.Builder
+	var last rune
+	for i, r := range word {
+		if i > 0 && strings.ContainsRune("AEIOUH", r) {
+			continue
+		}
+		if r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
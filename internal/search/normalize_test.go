@@ -0,0 +1,66 @@
+package search
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"  João  d'Ávila-Souza ", "JOAO DAVILA SOUZA"},
+		{"maria da silva", "MARIA DA SILVA"},
+		{"CONCEIÇÃO", "CONCEICAO"},
+		{"Ana\tPaula\nLima", "ANA PAULA LIMA"},
+		{"José (filho)", "JOSE FILHO"},
+		{"Ângela Müller", "ANGELA MULLER"},
+		{"O´Connor", "OCONNOR"},
+		{"Rua 25 de Março", "RUA 25 DE MARCO"},
+		{"", ""},
+		{"  ...  ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, esperado %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPhonetic(t *testing.T) {
+	// Grafias diferentes do mesmo nome precisam gerar a mesma chave
+	same := []struct{ a, b string }{
+		{"Thaís", "Taiz"},
+		{"Luiz", "Luis"},
+		{"Phelipe", "Felipe"},
+		{"Walter", "Valter"},
+		{"Cristina", "Kristina"},
+		{"Souza", "Sousa"},
+		{"Ygor", "Igor"},
+		{"Thaís Souza", "taiz sousa"},
+	}
+	for _, tt := range same {
+		if a, b := Phonetic(tt.a), Phonetic(tt.b); a != b {
+			t.Errorf("Phonetic(%q) = %q e Phonetic(%q) = %q, esperada a mesma chave", tt.a, a, tt.b, b)
+		}
+	}
+
+	// Nomes diferentes não podem colidir
+	different := []struct{ a, b string }{
+		{"Maria", "Marta"},
+		{"Ana", "Bruna"},
+		{"Paulo", "Pedro"},
+		{"Silva", "Souza"},
+	}
+	for _, tt := range different {
+		if a, b := Phonetic(tt.a), Phonetic(tt.b); a == b {
+			t.Errorf("Phonetic(%q) e Phonetic(%q) = %q, esperadas chaves diferentes", tt.a, tt.b, a)
+		}
+	}
+
+	if got := Phonetic("Ana Maria"); got != Phonetic("Ana")+" "+Phonetic("Maria") {
+		t.Errorf("Phonetic(%q) = %q, esperada uma chave por palavra", "Ana Maria", got)
+	}
+	if got := Phonetic(""); got != "" {
+		t.Errorf("Phonetic(\"\") = %q, esperado vazio", got)
+	}
+}
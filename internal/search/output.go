@@ -0,0 +1,133 @@
+package search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Formatos de saída da busca
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+)
+
+// Fuso usado para mostrar as datas, como no MongoDB Shell dos scripts
+var displayLocation = loadLocation("America/Sao_Paulo")
+
+// loadLocation carrega o fuso horário, usando o fuso local se ele não estiver disponível
+func loadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// Colunas das saídas em tabela e CSV
+var columns = []string{"cpf", "nome", "nasc", "cidade", "uf", "cep", "telefones", "emails", "data_atualizacao"}
+
+// CheckFormat retorna erro se o formato de saída não for suportado
+func CheckFormat(format string) error {
+	switch format {
+	case FormatTable, FormatJSON, FormatCSV, "":
+		return nil
+	}
+	return fmt.Errorf("formato de saída inválido: %q (use %s, %s ou %s)", format, FormatTable, FormatJSON, FormatCSV)
+}
+
+// Write escreve o resultado no formato pedido
+func Write(w io.Writer, format string, result *Result) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, result)
+	case FormatCSV:
+		return writeCSV(w, result)
+	}
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	return writeTable(w, result)
+}
+
+// writeTable escreve os resultados alinhados em colunas, seguidos da paginação e do tempo da busca
+func writeTable(w io.Writer, result *Result) error {
+	if len(result.People) == 0 {
+		fmt.Fprintln(w, "Nenhum resultado encontrado")
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, person := range result.People {
+			fmt.Fprintln(tw, strings.Join(row(person, ", "), "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\nPágina %d de %d - %d resultado(s) - tempo da busca: %v\n",
+		result.Page, result.Pages(), result.Total, result.Elapsed.Round(time.Millisecond))
+	return err
+}
+
+// writeJSON escreve o resultado com a paginação e o tempo da busca em milissegundos
+func writeJSON(w io.Writer, result *Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		*Result
+		Pages     int     `json:"paginas"`
+		ElapsedMs float64 `json:"tempo_ms"`
+	}{result, result.Pages(), float64(result.Elapsed.Microseconds()) / 1000})
+}
+
+// writeCSV escreve os resultados em CSV. Telefones e emails são separados por ponto e vírgula.
+// O tempo da busca não faz parte do CSV para não atrapalhar a importação
+func writeCSV(w io.Writer, result *Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, person := range result.People {
+		if err := writer.Write(row(person, ";")); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// row formata os campos de um resultado na ordem de columns
+func row(p Person, separator string) []string {
+	return []string{
+		p.CPF,
+		p.Nome,
+		formatDate(p.Nasc),
+		p.Cidade,
+		p.UF,
+		p.CEP,
+		strings.Join(p.Contatos.Telefones, separator),
+		strings.Join(p.Contatos.Emails, separator),
+		formatDateTime(p.DataAtualizacao),
+	}
+}
+
+// formatDate formata uma data sem hora. Essas datas são gravadas em UTC e não devem mudar de dia com o fuso
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format("02/01/2006")
+}
+
+// formatDateTime formata data e hora no fuso de exibição
+func formatDateTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(displayLocation).Format("02/01/2006 15:04:05")
+}
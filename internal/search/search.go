@@ -0,0 +1,327 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"MysqlToMongo/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Campos gravados pela migração para a busca por nome
+const (
+	NormalizedField = "nome_busca"
+	PhoneticField   = "nome_fonetico"
+)
+
+// Tamanho de página usado quando Options.PageSize não é informado
+const DefaultPageSize = 20
+
+// Criteria representa os critérios da busca. Os critérios informados são combinados com E
+type Criteria struct {
+	CPF      string
+	Phone    string
+	Email    string
+	Name     string // Busca por prefixo no nome normalizado
+	Phonetic bool   // Busca Name pela chave fonética
+	CEP      string
+	City     string
+
+	SearchKeys config.SearchConfig // Campos de busca gravados na migração; sem nome_busca a busca por nome usa o campo nome
+}
+
+// Options representa a paginação da busca
+type Options struct {
+	Page     int // Página a partir de 1
+	PageSize int
+}
+
+// Person representa os campos de um documento mostrados na busca
+type Person struct {
+	CPF             string     `bson:"cpf" json:"cpf"`
+	Nome            string     `bson:"nome" json:"nome"`
+	Nasc            *time.Time `bson:"nasc" json:"nasc,omitempty"`
+	Cidade          string     `bson:"cidade" json:"cidade"`
+	UF              string     `bson:"uf" json:"uf"`
+	CEP             string     `bson:"cep" json:"cep"`
+	DataAtualizacao *time.Time `bson:"data_atualizacao" json:"data_atualizacao,omitempty"`
+	Contatos        struct {
+		Telefones []string `bson:"telefones" json:"telefones"`
+		Emails    []string `bson:"emails" json:"emails"`
+	} `bson:"contatos" json:"contatos"`
+}
+
+// Pagination representa a página retornada e o total de documentos encontrados
+type Pagination struct {
+	Total    int64         `json:"total"` // Total de documentos encontrados, em todas as páginas
+	Page     int           `json:"pagina"`
+	PageSize int           `json:"tamanho_pagina"`
+	Elapsed  time.Duration `json:"-"`
+}
+
+// Pages retorna o número de páginas do resultado
+func (p Pagination) Pages() int {
+	if p.PageSize <= 0 {
+		return 0
+	}
+	return int((p.Total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
+
+// Result representa uma página de resultados
+type Result struct {
+	People []Person `json:"resultados"`
+	Pagination
+}
+
+// Documents representa uma página de documentos com apenas os campos pedidos
+type Documents struct {
+	Documents []bson.M `json:"resultados"`
+	Pagination
+}
+
+// DefaultFields são os campos retornados por Find
+var DefaultFields = []string{"cpf", "nome", "nasc", "cidade", "uf", "cep", "data_atualizacao", "contatos"}
+
+// fieldProjection monta a projeção que retorna apenas os campos informados, sem o _id
+func fieldProjection(fields []string) bson.D {
+	projection := bson.D{{Key: "_id", Value: 0}}
+	for _, field := range fields {
+		projection = append(projection, bson.E{Key: field, Value: 1})
+	}
+	return projection
+}
+
+// Filter monta o filtro do MongoDB para os critérios informados
+func Filter(c Criteria) (bson.D, error) {
+	filter := bson.D{}
+
+	if c.CPF != "" {
+		cpf := digits(c.CPF)
+		if cpf == "" || len(cpf) > 11 {
+			return nil, fmt.Errorf("CPF inválido: %q", c.CPF)
+		}
+		// A migração grava o CPF com zeros à esquerda
+		filter = append(filter, bson.E{Key: "cpf", Value: fmt.Sprintf("%011s", cpf)})
+	}
+	if c.Phone != "" {
+		phone := digits(c.Phone)
+		if phone == "" {
+			return nil, fmt.Errorf("telefone inválido: %q", c.Phone)
+		}
+		// A migração grava o telefone como está na origem, com ou sem pontuação
+		filter = append(filter, bson.E{Key: "contatos.telefones", Value: bson.D{{Key: "$in", Value: unique(strings.TrimSpace(c.Phone), phone)}}})
+	}
+	if c.Email != "" {
+		email := strings.TrimSpace(c.Email)
+		filter = append(filter, bson.E{Key: "contatos.emails", Value: bson.D{{Key: "$in", Value: unique(email, strings.ToLower(email))}}})
+	}
+	if c.Name != "" {
+		name, err := NameFilter(c.Name, c.Phonetic, c.SearchKeys)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, name...)
+	}
+	if c.CEP != "" {
+		cep := digits(c.CEP)
+		if len(cep) != 8 {
+			return nil, fmt.Errorf("CEP inválido: %q", c.CEP)
+		}
+		// O CEP pode ter sido gravado com ou sem o hífen
+		filter = append(filter, bson.E{Key: "cep", Value: bson.D{{Key: "$in", Value: bson.A{cep, cep[:5] + "-" + cep[5:]}}}})
+	}
+	if c.City != "" {
+		city := Normalize(c.City)
+		if city == "" {
+			return nil, fmt.Errorf("cidade vazia após a normalização")
+		}
+		filter = append(filter, bson.E{Key: "cidade", Value: bson.D{
+			{Key: "$regex", Value: "^" + accentInsensitive(city) + "$"},
+			{Key: "$options", Value: "i"},
+		}})
+	}
+
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("informe ao menos um critério de busca")
+	}
+	return filter, nil
+}
+
+// NameFilter monta o filtro da busca por nome. O valor é normalizado como na migração e buscado
+// por prefixo, o que usa o índice e ignora acentos e maiúsculas. Com phonetic a busca usa a chave fonética.
+// Se a migração não gravou nome_busca, o prefixo é buscado no nome com uma expressão que ignora acentos, sem índice
+func NameFilter(name string, phonetic bool, keys config.SearchConfig) (bson.D, error) {
+	if phonetic && !keys.PhoneticName {
+		return nil, fmt.Errorf("a busca fonética exige o campo %s, gravado na migração com search.phonetic_name", PhoneticField)
+	}
+	field, key := NormalizedField, Normalize(name)
+	if phonetic {
+		field, key = PhoneticField, Phonetic(name)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("nome vazio após a normalização")
+	}
+
+	if !phonetic && !keys.NormalizedName {
+		return bson.D{{Key: "nome", Value: bson.D{
+			{Key: "$regex", Value: "^" + accentInsensitive(key)},
+			{Key: "$options", Value: "i"},
+		}}}, nil
+	}
+
+	// Expressão ancorada no início para que o MongoDB percorra apenas o intervalo do índice
+	return bson.D{{Key: field, Value: prefixRegex(key)}}, nil
+}
+
+// prefixRegex monta a expressão que encontra valores que começam com o prefixo
+func prefixRegex(prefix string) bson.D {
+	return bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)}}
+}
+
+// Variações acentuadas de cada letra, usadas nos campos sem versão normalizada
+var accentClasses = map[rune]string{
+	'A': "[AÁÀÂÃÄ]", 'E': "[EÉÈÊË]", 'I': "[IÍÌÎÏ]", 'O': "[OÓÒÔÕÖ]", 'U': "[UÚÙÛÜ]", 'C': "[CÇ]", 'N': "[NÑ]",
+}
+
+// accentInsensitive monta uma expressão que encontra o valor normalizado com ou sem acentos e pontuação
+func accentInsensitive(normalized string) string {
+	var b strings.Builder
+	for _, r := range normalized {
+		switch {
+		case r == ' ':
+			b.WriteString(`[\s\p{P}]+`)
+		case accentClasses[r] != "":
+			b.WriteString(accentClasses[r])
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// Find executa a busca e retorna a página pedida com o total de documentos encontrados
+func Find(ctx context.Context, collection *mongo.Collection, c Criteria, opts Options) (*Result, error) {
+	result := &Result{People: []Person{}}
+	pagination, err := find(ctx, collection, c, opts, fieldProjection(DefaultFields), &result.People)
+	if err != nil {
+		return nil, err
+	}
+	result.Pagination = pagination
+	return result, nil
+}
+
+// FindDocuments executa a busca como Find, retornando apenas os campos pedidos de cada documento
+func FindDocuments(ctx context.Context, collection *mongo.Collection, c Criteria, opts Options, fields []string) (*Documents, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("informe ao menos um campo")
+	}
+	result := &Documents{Documents: []bson.M{}}
+	pagination, err := find(ctx, collection, c, opts, fieldProjection(fields), &result.Documents)
+	if err != nil {
+		return nil, err
+	}
+	result.Pagination = pagination
+	return result, nil
+}
+
+// find conta os documentos encontrados e decodifica a página pedida em results
+func find(ctx context.Context, collection *mongo.Collection, c Criteria, opts Options, projection bson.D, results interface{}) (Pagination, error) {
+	filter, err := Filter(c)
+	if err != nil {
+		return Pagination{}, err
+	}
+	pagination := Pagination{Page: opts.Page, PageSize: opts.PageSize}
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.PageSize <= 0 {
+		pagination.PageSize = DefaultPageSize
+	}
+
+	start := time.Now()
+	pagination.Total, err = collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return Pagination{}, fmt.Errorf("erro ao contar resultados: %v", err)
+	}
+
+	// A ordenação estável garante que as páginas não se sobreponham
+	sort := bson.D{{Key: "_id", Value: 1}}
+	if c.Name != "" {
+		sort = bson.D{{Key: nameSortField(c.SearchKeys), Value: 1}, {Key: "_id", Value: 1}}
+	}
+	findOpts := options.Find().
+		SetProjection(projection).
+		SetSort(sort).
+		SetSkip(int64(pagination.Page-1) * int64(pagination.PageSize)).
+		SetLimit(int64(pagination.PageSize))
+	cursor, err := collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return Pagination{}, fmt.Errorf("erro na busca: %v", err)
+	}
+	if err := cursor.All(ctx, results); err != nil {
+		return Pagination{}, fmt.Errorf("erro na busca: %v", err)
+	}
+	pagination.Elapsed = time.Since(start)
+	return pagination, nil
+}
+
+// nameSortField retorna o campo que ordena os resultados da busca por nome
+func nameSortField(keys config.SearchConfig) string {
+	if keys.NormalizedName {
+		return NormalizedField
+	}
+	return "nome"
+}
+
+// FindByName busca documentos pelo nome usando os campos de busca gravados na migração
+func FindByName(ctx context.Context, collection *mongo.Collection, name string, phonetic bool, keys config.SearchConfig, limit int64) ([]bson.M, error) {
+	filter, err := NameFilter(name, phonetic, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: nameSortField(keys), Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro na busca por nome: %v", err)
+	}
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("erro na busca por nome: %v", err)
+	}
+	return results, nil
+}
+
+// digits mantém apenas os dígitos do valor
+func digits(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// unique retorna os valores sem repetições, na ordem recebida
+func unique(values ...string) bson.A {
+	seen := make(map[string]bool, len(values))
+	result := bson.A{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
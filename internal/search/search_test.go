@@ -0,0 +1,94 @@
+package search
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"MysqlToMongo/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNameFilter(t *testing.T) {
+	all := config.SearchConfig{NormalizedName: true, PhoneticName: true}
+	tests := []struct {
+		name      string
+		value     string
+		phonetic  bool
+		keys      config.SearchConfig
+		wantField string
+		wantRegex string
+		wantErr   string
+	}{
+		{"prefixo normalizado", "João d'Ávila", false, all, NormalizedField, "^JOAO DAVILA", ""},
+		{"caracteres especiais escapados", "Ana (Maria)", false, all, NormalizedField, "^ANA MARIA", ""},
+		{"fonético", "Thaís", true, all, PhoneticField, "^" + Phonetic("Taiz"), ""},
+		{"sem nome_busca usa o nome", "Conceição", false, config.SearchConfig{}, "nome", "^" + accentInsensitive("CONCEICAO"), ""},
+		{"sem nome_fonetico", "Thaís", true, config.SearchConfig{NormalizedName: true}, "", "", "search.phonetic_name"},
+		{"nome vazio", " ... ", false, all, "", "", "nome vazio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NameFilter(tt.value, tt.phonetic, tt.keys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NameFilter() erro = %v, esperado %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(filter) != 1 || filter[0].Key != tt.wantField {
+				t.Fatalf("NameFilter() = %v, esperado filtro em %s", filter, tt.wantField)
+			}
+			regex := filter[0].Value.(bson.D).Map()["$regex"]
+			if regex != tt.wantRegex {
+				t.Errorf("$regex = %v, esperado %v", regex, tt.wantRegex)
+			}
+		})
+	}
+}
+
+func TestAccentInsensitive(t *testing.T) {
+	pattern := regexp.MustCompile("(?i)^" + accentInsensitive(Normalize("Conceição Ávila")) + "$")
+	for _, value := range []string{"CONCEIÇÃO ÁVILA", "conceicao avila", "Conceição  Ávila", "Conceição-Ávila"} {
+		if !pattern.MatchString(value) {
+			t.Errorf("%q não casa com %s", value, pattern)
+		}
+	}
+	if pattern.MatchString("Conceição Silva") {
+		t.Errorf("%q não deveria casar com %s", "Conceição Silva", pattern)
+	}
+}
+
+func TestFilterPhoneMatchesRawAndDigits(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  bson.A
+	}{
+		{"31996320718", bson.A{"31996320718"}},
+		{" (31) 99632-0718 ", bson.A{"(31) 99632-0718", "31996320718"}},
+	}
+
+	for _, tt := range tests {
+		filter, err := Filter(Criteria{Phone: tt.phone})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filter) != 1 || filter[0].Key != "contatos.telefones" {
+			t.Fatalf("Filter() = %v, esperado filtro em contatos.telefones", filter)
+		}
+		got := filter[0].Value.(bson.D).Map()["$in"]
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("telefone %q: $in = %v, esperado %v", tt.phone, got, tt.want)
+		}
+	}
+
+	if _, err := Filter(Criteria{Phone: "sem número"}); err == nil {
+		t.Error("telefone sem dígitos deveria ser recusado")
+	}
+}
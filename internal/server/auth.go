@@ -0,0 +1,176 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/logging"
+)
+
+// apiKey representa uma chave de acesso e o seu limite de requisições
+type apiKey struct {
+	name   string
+	key    []byte
+	bucket *bucket
+}
+
+// newAPIKeys valida as chaves configuradas e cria o limite de cada uma
+func newAPIKeys(cfg config.ServerConfig) ([]*apiKey, error) {
+	if len(cfg.APIKeys) == 0 {
+		return nil, fmt.Errorf("nenhuma chave de acesso configurada em server.api_keys")
+	}
+	defaultLimit := cfg.RequestsPerMinute
+	if defaultLimit <= 0 {
+		defaultLimit = defaultRequestsPerMinute
+	}
+
+	keys := make([]*apiKey, 0, len(cfg.APIKeys))
+	seen := make(map[string]bool, len(cfg.APIKeys))
+	for i, k := range cfg.APIKeys {
+		if k.Name == "" || k.Key == "" {
+			return nil, fmt.Errorf("server.api_keys[%d]: name e key são obrigatórios", i)
+		}
+		if seen[k.Key] {
+			return nil, fmt.Errorf("server.api_keys[%d]: chave repetida", i)
+		}
+		seen[k.Key] = true
+
+		limit := k.RequestsPerMinute
+		if limit <= 0 {
+			limit = defaultLimit
+		}
+		keys = append(keys, &apiKey{name: k.Name, key: []byte(k.Key), bucket: newBucket(limit)})
+	}
+	return keys, nil
+}
+
+// lookup retorna a chave correspondente ao valor recebido, comparando em tempo constante
+func (s *Server) lookup(value string) *apiKey {
+	var found *apiKey
+	for _, k := range s.keys {
+		if subtle.ConstantTimeCompare(k.key, []byte(value)) == 1 {
+			found = k
+		}
+	}
+	return found
+}
+
+// requestKey extrai a chave do cabeçalho X-API-Key ou Authorization: Bearer.
+// A chave não é aceita na query string para não aparecer nos logs
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// authorized exige uma chave de acesso válida e aplica o limite de requisições da chave
+func (s *Server) authorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := s.lookup(requestKey(r))
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "chave de acesso ausente ou inválida")
+			return
+		}
+		if recorder, ok := w.(*statusRecorder); ok {
+			recorder.keyName = key.name
+		}
+
+		if ok, wait := key.bucket.take(time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "limite de requisições excedido")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder guarda o status da resposta e a chave usada para o log da requisição
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	keyName string
+}
+
+// WriteHeader guarda o status antes de enviá-lo
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logged registra cada requisição com a chave usada, o status e o tempo de resposta
+func (s *Server) logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK, keyName: "-"}
+		next.ServeHTTP(recorder, r)
+		logging.Infof("%s %s %s chave=%s status=%d tempo=%v",
+			r.RemoteAddr, r.Method, logTarget(r), recorder.keyName, recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// logTarget descreve a requisição para o log sem dados pessoais: o CPF do caminho é omitido
+// e da query string ficam apenas os nomes dos parâmetros
+func logTarget(r *http.Request) string {
+	path := r.URL.Path
+	if rest, ok := strings.CutPrefix(path, "/pessoas/"); ok && rest != "" {
+		path = "/pessoas/{cpf}"
+	}
+
+	query := r.URL.Query()
+	if len(query) == 0 {
+		return path
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return path + "?" + strings.Join(names, "&")
+}
+
+// bucket limita as requisições de uma chave. Comporta até um minuto de requisições
+// em rajada e é reabastecido continuamente
+type bucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64 // Requisições por segundo
+	last     time.Time
+}
+
+// newBucket cria um limite de requisições por minuto, começando cheio
+func newBucket(perMinute int) *bucket {
+	return &bucket{
+		tokens:   float64(perMinute),
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// take consome uma requisição. Sem saldo, retorna quanto tempo falta para a próxima
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
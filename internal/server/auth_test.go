@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		takes     []time.Duration // Momento de cada requisição, a partir da criação
+		want      []bool
+		wantWait  time.Duration // Espera informada na última requisição recusada
+	}{
+		{
+			name:      "rajada até a capacidade",
+			perMinute: 3,
+			takes:     []time.Duration{0, 0, 0, 0},
+			want:      []bool{true, true, true, false},
+			wantWait:  20 * time.Second,
+		},
+		{
+			name:      "reabastece continuamente",
+			perMinute: 60,
+			takes:     append(repeat(0, 60), 0, 500*time.Millisecond, time.Second),
+			want:      append(repeatBool(true, 60), false, false, true),
+			wantWait:  500 * time.Millisecond,
+		},
+		{
+			name:      "saldo não passa da capacidade",
+			perMinute: 2,
+			takes:     []time.Duration{time.Hour, time.Hour, time.Hour},
+			want:      []bool{true, true, false},
+			wantWait:  30 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBucket(tt.perMinute)
+			start := b.last
+			var wait time.Duration
+			for i, at := range tt.takes {
+				ok, w := b.take(start.Add(at))
+				if ok != tt.want[i] {
+					t.Fatalf("requisição %d em %v: take() = %v, esperado %v", i+1, at, ok, tt.want[i])
+				}
+				if !ok {
+					wait = w
+				}
+			}
+			if diff := wait - tt.wantWait; diff > time.Millisecond || diff < -time.Millisecond {
+				t.Errorf("espera = %v, esperado %v", wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func repeat(d time.Duration, n int) []time.Duration {
+	values := make([]time.Duration, n)
+	for i := range values {
+		values[i] = d
+	}
+	return values
+}
+
+func repeatBool(b bool, n int) []bool {
+	values := make([]bool, n)
+	for i := range values {
+		values[i] = b
+	}
+	return values
+}
+
+func TestLogTarget(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"/health", "/health"},
+		{"/pessoas/12345678900", "/pessoas/{cpf}"},
+		{"/pessoas/123.456.789-00?fields=nome", "/pessoas/{cpf}?fields"},
+		{"/pessoas?nome=maria%20silva&telefone=11999998888&email=a%40b.com&page=2", "/pessoas?email&nome&page&telefone"},
+		{"/pessoas?cpf=1&cpf=2", "/pessoas?cpf"},
+		{"/pessoas/", "/pessoas/"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.url, nil)
+		if got := logTarget(r); got != tt.want {
+			t.Errorf("logTarget(%q) = %q, esperado %q", tt.url, got, tt.want)
+		}
+	}
+}
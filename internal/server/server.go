@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/logging"
+	"MysqlToMongo/internal/search"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Valores usados quando a configuração do servidor não informa
+const (
+	defaultAddr              = ":8080"
+	defaultRequestsPerMinute = 60
+	defaultMaxPageSize       = 100
+	defaultQueryTimeout      = 10 * time.Second
+	shutdownTimeout          = 10 * time.Second
+)
+
+// Server expõe uma API HTTP somente leitura sobre a collection migrada
+type Server struct {
+	collection   *mongo.Collection
+	searchKeys   config.SearchConfig
+	keys         []*apiKey
+	fields       []string
+	allowed      map[string]bool
+	maxPageSize  int
+	queryTimeout time.Duration
+}
+
+// New cria o servidor. searchKeys informa os campos de busca gravados na migração.
+// Retorna erro se não houver chaves de acesso configuradas
+func New(cfg config.ServerConfig, searchKeys config.SearchConfig, collection *mongo.Collection) (*Server, error) {
+	keys, err := newAPIKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := cfg.Fields
+	if len(fields) == 0 {
+		fields = search.DefaultFields
+	}
+	allowed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		allowed[field] = true
+	}
+
+	s := &Server{
+		collection:   collection,
+		searchKeys:   searchKeys,
+		keys:         keys,
+		fields:       fields,
+		allowed:      allowed,
+		maxPageSize:  cfg.MaxPageSize,
+		queryTimeout: time.Duration(cfg.QueryTimeoutMs) * time.Millisecond,
+	}
+	if s.maxPageSize <= 0 {
+		s.maxPageSize = defaultMaxPageSize
+	}
+	if s.queryTimeout <= 0 {
+		s.queryTimeout = defaultQueryTimeout
+	}
+	return s, nil
+}
+
+// Handler retorna as rotas da API. /health não exige chave de acesso
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/health", s.logged(http.HandlerFunc(s.health)))
+	mux.Handle("/pessoas", s.logged(s.authorized(http.HandlerFunc(s.searchPeople))))
+	mux.Handle("/pessoas/", s.logged(s.authorized(http.HandlerFunc(s.getByCPF))))
+	return mux
+}
+
+// ListenAndServe atende as requisições em addr até o contexto ser cancelado,
+// aguardando as requisições em andamento antes de retornar
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = defaultAddr
+	}
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      s.queryTimeout + 5*time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		logging.Infof("API de consulta ouvindo em %s", addr)
+		errChan <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("erro no servidor HTTP: %v", err)
+	case <-ctx.Done():
+	}
+
+	logging.Info("Encerrando a API de consulta...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("erro ao encerrar o servidor HTTP: %v", err)
+	}
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("erro no servidor HTTP: %v", err)
+	}
+	return nil
+}
+
+// health verifica a conexão com o MongoDB
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+	if err := s.collection.Database().Client().Ping(ctx, nil); err != nil {
+		logging.Warnf("health check falhou: %v", err)
+		writeError(w, http.StatusServiceUnavailable, "MongoDB indisponível")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// getByCPF retorna o documento do CPF em GET /pessoas/{cpf}
+func (s *Server) getByCPF(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	cpf := strings.TrimPrefix(r.URL.Path, "/pessoas/")
+	if cpf == "" || strings.Contains(cpf, "/") {
+		writeError(w, http.StatusNotFound, "recurso não encontrado")
+		return
+	}
+	fields, err := s.requestedFields(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	criteria := search.Criteria{CPF: cpf, SearchKeys: s.searchKeys}
+	if _, err := search.Filter(criteria); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, ok := s.find(w, r, criteria, search.Options{Page: 1, PageSize: 1}, fields)
+	if !ok {
+		return
+	}
+	if len(result.Documents) == 0 {
+		writeError(w, http.StatusNotFound, "CPF não encontrado")
+		return
+	}
+	writeJSON(w, http.StatusOK, result.Documents[0])
+}
+
+// searchPeople busca documentos em GET /pessoas pelos critérios da query string, que são combinados com E
+func (s *Server) searchPeople(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	criteria := search.Criteria{
+		CPF:      query.Get("cpf"),
+		Phone:    query.Get("telefone"),
+		Email:    query.Get("email"),
+		Name:     query.Get("nome"),
+		Phonetic: query.Get("fonetico") == "true",
+		CEP:      query.Get("cep"),
+		City:     query.Get("cidade"),
+
+		SearchKeys: s.searchKeys,
+	}
+	if _, err := search.Filter(criteria); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts, err := s.pagination(query.Get("page"), query.Get("page_size"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fields, err := s.requestedFields(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, ok := s.find(w, r, criteria, opts, fields)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*search.Documents
+		Pages int `json:"paginas"`
+	}{result, result.Pages()})
+}
+
+// find executa a busca com o tempo limite de consulta. Em caso de erro responde e retorna false
+func (s *Server) find(w http.ResponseWriter, r *http.Request, c search.Criteria, opts search.Options, fields []string) (*search.Documents, bool) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	result, err := search.FindDocuments(ctx, s.collection, c, opts, fields)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, "tempo limite da consulta excedido")
+			return nil, false
+		}
+		logging.Errorf("Erro na consulta %s: %v", logTarget(r), err)
+		writeError(w, http.StatusInternalServerError, "erro interno")
+		return nil, false
+	}
+	return result, true
+}
+
+// pagination interpreta os parâmetros page e page_size
+func (s *Server) pagination(page, pageSize string) (search.Options, error) {
+	opts := search.Options{Page: 1, PageSize: search.DefaultPageSize}
+	if page != "" {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			return opts, fmt.Errorf("page inválido: %q", page)
+		}
+		opts.Page = value
+	}
+	if pageSize != "" {
+		value, err := strconv.Atoi(pageSize)
+		if err != nil || value < 1 || value > s.maxPageSize {
+			return opts, fmt.Errorf("page_size deve estar entre 1 e %d", s.maxPageSize)
+		}
+		opts.PageSize = value
+	}
+	return opts, nil
+}
+
+// requestedFields interpreta o parâmetro fields, uma lista separada por vírgulas.
+// Sem o parâmetro retorna todos os campos permitidos
+func (s *Server) requestedFields(r *http.Request) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return s.fields, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !s.allowed[field] {
+			return nil, fmt.Errorf("campo não permitido: %q (permitidos: %s)", field, strings.Join(s.fields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return s.fields, nil
+	}
+	return fields, nil
+}
+
+// allowGet responde 405 para métodos diferentes de GET e HEAD
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeError(w, http.StatusMethodNotAllowed, "método não permitido")
+	return false
+}
+
+// writeJSON escreve a resposta em JSON
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.Errorf("Erro ao escrever resposta: %v", err)
+	}
+}
+
+// writeError escreve uma resposta de erro no formato {"erro": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"erro": message})
+}
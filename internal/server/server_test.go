@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"MysqlToMongo/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// Chaves de acesso usadas nos testes
+var testConfig = config.ServerConfig{
+	APIKeys: []config.APIKeyConfig{
+		{Name: "app", Key: "chave-app"},
+		{Name: "lenta", Key: "chave-lenta", RequestsPerMinute: 2},
+	},
+	Fields:      []string{"cpf", "nome", "cidade"},
+	MaxPageSize: 50,
+}
+
+// Campos de busca gravados na migração
+var testSearchKeys = config.SearchConfig{NormalizedName: true, PhoneticName: true}
+
+// newTestServer cria o servidor sobre a collection informada
+func newTestServer(t *testing.T, collection *mongo.Collection) http.Handler {
+	t.Helper()
+	s, err := New(testConfig, testSearchKeys, collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Handler()
+}
+
+// request executa a requisição no handler e decodifica a resposta JSON
+func request(t *testing.T, handler http.Handler, method, url, key string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	r := httptest.NewRequest(method, url, nil)
+	if key != "" {
+		r.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: resposta não é JSON: %q", method, url, w.Body.String())
+	}
+	return w, body
+}
+
+func TestNewRequiresKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []config.APIKeyConfig
+		wantErr string
+	}{
+		{"sem chaves", nil, "nenhuma chave"},
+		{"chave sem nome", []config.APIKeyConfig{{Key: "x"}}, "obrigatórios"},
+		{"chave repetida", []config.APIKeyConfig{{Name: "a", Key: "x"}, {Name: "b", Key: "x"}}, "chave repetida"},
+	}
+	for _, tt := range tests {
+		_, err := New(config.ServerConfig{APIKeys: tt.keys}, testSearchKeys, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: New() erro = %v, esperado %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// Requisições respondidas sem consultar o MongoDB
+func TestRequestValidation(t *testing.T) {
+	handler := newTestServer(t, nil)
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		key        string
+		header     string // Cabeçalho de autorização no lugar de X-API-Key
+		wantStatus int
+		wantError  string
+	}{
+		{"sem chave", "GET", "/pessoas?nome=maria", "", "", http.StatusUnauthorized, "chave de acesso"},
+		{"chave inválida", "GET", "/pessoas?nome=maria", "errada", "", http.StatusUnauthorized, "chave de acesso"},
+		{"chave na query string", "GET", "/pessoas?api_key=chave-app", "", "", http.StatusUnauthorized, "chave de acesso"},
+		{"bearer inválido", "GET", "/pessoas", "", "Bearer errada", http.StatusUnauthorized, "chave de acesso"},
+		{"bearer válido", "POST", "/pessoas", "", "Bearer chave-app", http.StatusMethodNotAllowed, "método não permitido"},
+		{"método não permitido", "DELETE", "/pessoas/12345678900", "chave-app", "", http.StatusMethodNotAllowed, "método não permitido"},
+		{"cpf inválido no caminho", "GET", "/pessoas/abc", "chave-app", "", http.StatusBadRequest, "CPF inválido"},
+		{"cpf longo demais", "GET", "/pessoas/123456789012", "chave-app", "", http.StatusBadRequest, "CPF inválido"},
+		{"caminho sem cpf", "GET", "/pessoas/", "chave-app", "", http.StatusNotFound, "recurso não encontrado"},
+		{"caminho com subrecurso", "GET", "/pessoas/123/contatos", "chave-app", "", http.StatusNotFound, "recurso não encontrado"},
+		{"telefone inválido", "GET", "/pessoas?telefone=abc", "chave-app", "", http.StatusBadRequest, "telefone inválido"},
+		{"página inválida", "GET", "/pessoas?nome=maria&page=0", "chave-app", "", http.StatusBadRequest, "page inválido"},
+		{"página grande demais", "GET", "/pessoas?nome=maria&page_size=51", "chave-app", "", http.StatusBadRequest, "entre 1 e 50"},
+		{"campo não permitido", "GET", "/pessoas?nome=maria&fields=nome,renda", "chave-app", "", http.StatusBadRequest, "campo não permitido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, esperado %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !strings.Contains(body["erro"], tt.wantError) {
+				t.Errorf("resposta = %s, esperado erro com %q", w.Body.String(), tt.wantError)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Error("resposta 401 sem WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := newTestServer(t, nil)
+
+	// A chave "lenta" permite duas requisições por minuto; requisições recusadas por método também contam
+	for i := 1; i <= 2; i++ {
+		if w, _ := request(t, handler, "POST", "/pessoas", "chave-lenta"); w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("requisição %d: status = %d, esperado 405", i, w.Code)
+		}
+	}
+	w, body := request(t, handler, "POST", "/pessoas", "chave-lenta")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("terceira requisição: status = %d, esperado 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Retry-After = %q, esperado 30", w.Header().Get("Retry-After"))
+	}
+	if body["erro"] != "limite de requisições excedido" {
+		t.Errorf("resposta = %v", body)
+	}
+
+	// O limite é por chave
+	if w, _ := request(t, handler, "POST", "/pessoas", "chave-app"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("outra chave: status = %d, esperado 405", w.Code)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// Respostas do MongoDB: CountDocuments é um aggregate e Find devolve a primeira página do cursor
+	count := func(mt *mtest.T, n int) bson.D {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: n}})
+	}
+	page := func(mt *mtest.T, docs ...bson.D) bson.D {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...)
+	}
+	maria := bson.D{{Key: "cpf", Value: "00012345678"}, {Key: "nome", Value: "MARIA"}, {Key: "cidade", Value: "SAO PAULO"}}
+	mario := bson.D{{Key: "cpf", Value: "00012345679"}, {Key: "nome", Value: "MARIO"}, {Key: "cidade", Value: "SANTOS"}}
+
+	mt.Run("health", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		w, body := request(mt.T, newTestServer(mt.T, mt.Coll), "GET", "/health", "")
+		if w.Code != http.StatusOK || body["status"] != "ok" {
+			mt.Errorf("health = %d %v, esperado 200 ok", w.Code, body)
+		}
+	})
+
+	mt.Run("cpf encontrado", func(mt *mtest.T) {
+		mt.AddMockResponses(count(mt, 1), page(mt, maria))
+		w, body := request(mt.T, newTestServer(mt.T, mt.Coll), "GET", "/pessoas/123.456-78?fields=cpf,nome", "chave-app")
+		if w.Code != http.StatusOK || body["cpf"] != "00012345678" || body["nome"] != "MARIA" {
+			mt.Fatalf("GET /pessoas/{cpf} = %d %v", w.Code, body)
+		}
+
+		// O CPF é buscado com zeros à esquerda e apenas os campos pedidos são projetados
+		find := mt.GetStartedEvent()
+		for find != nil && find.CommandName != "find" {
+			find = mt.GetStartedEvent()
+		}
+		if find == nil {
+			mt.Fatal("nenhum comando find enviado")
+		}
+		if cpf := find.Command.Lookup("filter", "cpf").StringValue(); cpf != "00012345678" {
+			mt.Errorf("filtro do cpf = %q, esperado 00012345678", cpf)
+		}
+		projection, _ := find.Command.Lookup("projection").Document().Elements()
+		if len(projection) != 3 {
+			mt.Errorf("projeção = %v, esperados _id, cpf e nome", find.Command.Lookup("projection"))
+		}
+	})
+
+	mt.Run("cpf não encontrado", func(mt *mtest.T) {
+		mt.AddMockResponses(count(mt, 0), page(mt))
+		w, body := request(mt.T, newTestServer(mt.T, mt.Coll), "GET", "/pessoas/12345678900", "chave-app")
+		if w.Code != http.StatusNotFound || body["erro"] != "CPF não encontrado" {
+			mt.Errorf("GET /pessoas/{cpf} = %d %v, esperado 404", w.Code, body)
+		}
+	})
+
+	mt.Run("busca paginada", func(mt *mtest.T) {
+		mt.AddMockResponses(count(mt, 3), page(mt, maria, mario))
+		w, body := request(mt.T, newTestServer(mt.T, mt.Coll), "GET", "/pessoas?nome=mari&page_size=2", "chave-app")
+		if w.Code != http.StatusOK {
+			mt.Fatalf("GET /pessoas = %d %v", w.Code, body)
+		}
+		results, _ := body["resultados"].([]interface{})
+		if len(results) != 2 || body["total"] != float64(3) || body["paginas"] != float64(2) || body["tamanho_pagina"] != float64(2) {
+			mt.Errorf("GET /pessoas = %v, esperados 2 resultados de 3 em 2 páginas", body)
+		}
+	})
+
+	mt.Run("erro do MongoDB", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "falha", Name: "BadValue"}))
+		w, body := request(mt.T, newTestServer(mt.T, mt.Coll), "GET", "/pessoas?cidade=santos", "chave-app")
+		if w.Code != http.StatusInternalServerError || body["erro"] != "erro interno" {
+			mt.Errorf("GET /pessoas = %d %v, esperado 500 sem detalhes", w.Code, body)
+		}
+	})
+}
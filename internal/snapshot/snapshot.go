@@ -0,0 +1,178 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Position representa o ponto do binlog em que o snapshot foi aberto.
+// Uma carga incremental (CDC) deve começar a partir dele
+type Position struct {
+	CapturedAt   time.Time `json:"captured_at"`
+	Table        string    `json:"table"`
+	BinlogFile   string    `json:"binlog_file,omitempty"`
+	BinlogPos    uint64    `json:"binlog_position,omitempty"`
+	GTIDExecuted string    `json:"gtid_executed,omitempty"`
+}
+
+// String descreve a posição para o log
+func (p Position) String() string {
+	if p.BinlogFile == "" && p.GTIDExecuted == "" {
+		return "binlog desativado"
+	}
+	desc := fmt.Sprintf("binlog %s:%d", p.BinlogFile, p.BinlogPos)
+	if p.GTIDExecuted != "" {
+		desc += fmt.Sprintf(", GTID %s", p.GTIDExecuted)
+	}
+	return desc
+}
+
+// Snapshot mantém uma conexão por leitor, todas com uma transação aberta no mesmo ponto no tempo
+type Snapshot struct {
+	Conns    []*sql.Conn
+	Position Position
+}
+
+// Open abre n conexões com transações WITH CONSISTENT SNAPSHOT no mesmo ponto no tempo.
+// A tabela fica bloqueada para escrita apenas enquanto as transações são abertas e a posição do binlog é lida
+func Open(ctx context.Context, db *sql.DB, table string, n int) (*Snapshot, error) {
+	coordinator, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir conexão do snapshot: %v", err)
+	}
+	defer coordinator.Close()
+
+	// Bloqueia as escritas na tabela para que todos os snapshots e a posição do binlog coincidam
+	if _, err := coordinator.ExecContext(ctx, fmt.Sprintf("LOCK TABLES %s READ", table)); err != nil {
+		return nil, fmt.Errorf("erro ao bloquear a tabela '%s': %v", table, err)
+	}
+	defer coordinator.ExecContext(context.WithoutCancel(ctx), "UNLOCK TABLES")
+
+	snap := &Snapshot{}
+	for i := 0; i < n; i++ {
+		conn, err := begin(ctx, db)
+		if err != nil {
+			snap.Close()
+			return nil, err
+		}
+		snap.Conns = append(snap.Conns, conn)
+	}
+
+	position, err := readPosition(ctx, coordinator)
+	if err != nil {
+		snap.Close()
+		return nil, err
+	}
+	position.Table = table
+	snap.Position = position
+
+	return snap, nil
+}
+
+// begin abre uma conexão com uma transação somente leitura no snapshot atual
+func begin(ctx context.Context, db *sql.DB) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir conexão do snapshot: %v", err)
+	}
+	for _, stmt := range []string{
+		"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("erro ao iniciar transação do snapshot: %v", err)
+		}
+	}
+	return conn, nil
+}
+
+// readPosition lê a posição atual do binlog e o conjunto de GTIDs executados.
+// Servidores sem binlog retornam uma posição vazia
+func readPosition(ctx context.Context, conn *sql.Conn) (Position, error) {
+	position := Position{CapturedAt: time.Now()}
+
+	// SHOW MASTER STATUS foi substituído por SHOW BINARY LOG STATUS no MySQL 8.4
+	rows, err := conn.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		rows, err = conn.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+		if err != nil {
+			return position, fmt.Errorf("erro ao ler a posição do binlog: %v", err)
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return position, err
+	}
+	if !rows.Next() {
+		return position, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return position, err
+	}
+
+	for i, column := range columns {
+		switch column {
+		case "File":
+			position.BinlogFile = values[i].String
+		case "Position":
+			offset, err := strconv.ParseUint(values[i].String, 10, 64)
+			if err != nil {
+				return position, fmt.Errorf("posição do binlog inválida '%s'", values[i].String)
+			}
+			position.BinlogPos = offset
+		case "Executed_Gtid_Set":
+			position.GTIDExecuted = values[i].String
+		}
+	}
+	return position, nil
+}
+
+// Close encerra as transações e devolve as conexões
+func (s *Snapshot) Close() {
+	if s == nil {
+		return
+	}
+	for _, conn := range s.Conns {
+		conn.ExecContext(context.Background(), "ROLLBACK")
+		conn.Close()
+	}
+	s.Conns = nil
+}
+
+// Connections retorna as conexões dos leitores, ou nenhuma se o snapshot não estiver ativo
+func (s *Snapshot) Connections() []*sql.Conn {
+	if s == nil {
+		return nil
+	}
+	return s.Conns
+}
+
+// SavePosition grava a posição em disco para a carga incremental seguinte
+func SavePosition(path string, position Position) error {
+	data, err := json.MarshalIndent(position, "", "    ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar posição do snapshot: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório da posição do snapshot: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar posição do snapshot: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer simula um MySQL que registra os comandos de cada conexão
+type fakeServer struct {
+	mu         sync.Mutex
+	log        []string // "conexão: comando"
+	conns      int
+	masterCmd  bool     // Aceita SHOW MASTER STATUS; sem ele apenas SHOW BINARY LOG STATUS (MySQL 8.4)
+	columns    []string // Colunas do status do binlog
+	status     []driver.Value
+	failBegins bool
+}
+
+func (s *fakeServer) Open(string) (driver.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns++
+	return &fakeConn{server: s, id: s.conns}, nil
+}
+
+func (s *fakeServer) record(id int, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, fmt.Sprintf("%d: %s", id, query))
+}
+
+type fakeConn struct {
+	server *fakeServer
+	id     int
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("sem prepare") }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("sem transações") }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.server.record(c.id, query)
+	if c.server.failBegins && strings.HasPrefix(query, "START TRANSACTION") {
+		return nil, errors.New("falha ao iniciar")
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.server.record(c.id, query)
+	if query == "SHOW MASTER STATUS" && !c.server.masterCmd {
+		return nil, errors.New("sintaxe desconhecida")
+	}
+	rows := &fakeRows{columns: c.server.columns}
+	if c.server.status != nil {
+		rows.values = [][]driver.Value{c.server.status}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var driverCount int
+
+// openFake registra o servidor simulado como um driver novo
+func openFake(t *testing.T, server *fakeServer) *sql.DB {
+	driverCount++
+	name := fmt.Sprintf("snapshot-%d", driverCount)
+	sql.Register(name, server)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var binlogColumns = []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}
+
+func TestOpen(t *testing.T) {
+	server := &fakeServer{
+		masterCmd: true,
+		columns:   binlogColumns,
+		status:    []driver.Value{[]byte("binlog.000042"), []byte("1337"), []byte(""), []byte(""), []byte("uuid:1-100")},
+	}
+	db := openFake(t, server)
+
+	snap, err := Open(context.Background(), db, "pessoas", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Position{Table: "pessoas", BinlogFile: "binlog.000042", BinlogPos: 1337, GTIDExecuted: "uuid:1-100"}
+	got := snap.Position
+	got.CapturedAt = want.CapturedAt
+	if got != want {
+		t.Errorf("Position = %+v, esperado %+v", got, want)
+	}
+	if len(snap.Connections()) != 2 {
+		t.Fatalf("%d conexões, esperado 2", len(snap.Connections()))
+	}
+	snap.Close()
+
+	// As transações são abertas e a posição lida com a tabela bloqueada
+	wantLog := []string{
+		"1: LOCK TABLES pessoas READ",
+		"2: SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"2: START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+		"3: SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"3: START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+		"1: SHOW MASTER STATUS",
+		"1: UNLOCK TABLES",
+		"2: ROLLBACK",
+		"3: ROLLBACK",
+	}
+	if !reflect.DeepEqual(server.log, wantLog) {
+		t.Errorf("comandos:\n%s\nesperado:\n%s", strings.Join(server.log, "\n"), strings.Join(wantLog, "\n"))
+	}
+}
+
+func TestOpenReleasesLockOnFailure(t *testing.T) {
+	server := &fakeServer{masterCmd: true, failBegins: true}
+	db := openFake(t, server)
+
+	if _, err := Open(context.Background(), db, "pessoas", 2); err == nil {
+		t.Fatal("Open() deveria falhar quando a transação não inicia")
+	}
+	if last := server.log[len(server.log)-1]; last != "1: UNLOCK TABLES" {
+		t.Errorf("último comando %q, esperado o desbloqueio da tabela", last)
+	}
+}
+
+func TestReadPositionOnMySQL84AndWithoutBinlog(t *testing.T) {
+	server := &fakeServer{
+		columns: binlogColumns,
+		status:  []driver.Value{[]byte("binlog.000007"), []byte("4"), nil, nil, nil},
+	}
+	snap, err := Open(context.Background(), openFake(t, server), "pessoas", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap.Close()
+	if snap.Position.BinlogFile != "binlog.000007" || snap.Position.BinlogPos != 4 {
+		t.Errorf("Position = %+v, esperado lida do SHOW BINARY LOG STATUS", snap.Position)
+	}
+
+	// Sem binlog o status não tem linhas e a posição fica vazia
+	snap, err = Open(context.Background(), openFake(t, &fakeServer{masterCmd: true, columns: binlogColumns}), "pessoas", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap.Close()
+	if snap.Position.String() != "binlog desativado" {
+		t.Errorf("Position = %s, esperado binlog desativado", snap.Position)
+	}
+}
+
+func TestSavePosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs", "pessoas", "snapshot_position.json")
+	position := Position{Table: "pessoas", BinlogFile: "binlog.000042", BinlogPos: 1337}
+	if err := SavePosition(path, position); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Position
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.BinlogFile != position.BinlogFile || saved.BinlogPos != position.BinlogPos || saved.Table != position.Table {
+		t.Errorf("posição gravada %+v, esperado %+v", saved, position)
+	}
+}
@@ -0,0 +1,343 @@
+package throttle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"MysqlToMongo/internal/config"
+	"MysqlToMongo/internal/logging"
+)
+
+// Valores padrão do modo adaptativo
+const (
+	defaultInterval = 5 * time.Second
+	defaultMinLevel = 0.05
+)
+
+// Fatores aplicados ao nível a cada consulta: reduz rápido quando há sobrecarga e recupera aos poucos
+const (
+	decreaseFactor = 0.5
+	increaseFactor = 1.25
+)
+
+// Atraso acumulado abaixo do qual o limitador não dorme, para evitar esperas muito curtas a cada linha
+const minSleep = 10 * time.Millisecond
+
+// limiter espaça os eventos para respeitar uma taxa por segundo. Taxa zero significa sem limite
+type limiter struct {
+	mu   sync.Mutex
+	rate float64
+	next time.Time
+}
+
+// setRate altera a taxa do limitador
+func (l *limiter) setRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+}
+
+// wait aguarda até que n eventos possam acontecer dentro da taxa
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	l.mu.Unlock()
+
+	if delay < minSleep {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Throttler limita a velocidade da leitura e da gravação e, no modo adaptativo,
+// ajusta o nível conforme a carga do MySQL de origem
+type Throttler struct {
+	cfg      config.ThrottleConfig
+	db       *sql.DB // Servidor de origem
+	replica  *sql.DB // Servidor consultado para o atraso de replicação
+	rows     limiter
+	batches  limiter
+	rowsRead atomic.Int64
+
+	mu       sync.Mutex
+	level    float64 // Fração da velocidade máxima permitida (0 a 1)
+	baseline float64 // Velocidade de referência quando não há limite de linhas configurado
+	reason   string  // Motivo da última redução
+}
+
+// Validate verifica os limites configurados sem acessar o MySQL
+func Validate(cfg config.ThrottleConfig) error {
+	if cfg.RowsPerSecond < 0 || cfg.BatchesPerSecond < 0 {
+		return fmt.Errorf("rows_per_second e batches_per_second não podem ser negativos")
+	}
+	adaptive := cfg.Adaptive
+	if adaptive.Enabled && adaptive.MaxThreadsRunning <= 0 && adaptive.MaxReplicaLagSeconds <= 0 && adaptive.MaxQueryLatencyMs <= 0 {
+		return fmt.Errorf("o modo adaptativo precisa de ao menos um limite: max_threads_running, max_replica_lag_seconds ou max_query_latency_ms")
+	}
+	return nil
+}
+
+// New cria o throttler a partir da configuração. Retorna nil quando nenhum limite está configurado
+func New(cfg config.ThrottleConfig, db, replica *sql.DB) (*Throttler, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.RowsPerSecond == 0 && cfg.BatchesPerSecond == 0 && !cfg.Adaptive.Enabled {
+		return nil, nil
+	}
+
+	adaptive := cfg.Adaptive
+	if adaptive.MinLevel <= 0 || adaptive.MinLevel > 1 {
+		cfg.Adaptive.MinLevel = defaultMinLevel
+	}
+	if replica == nil {
+		replica = db
+	}
+
+	t := &Throttler{cfg: cfg, db: db, replica: replica, level: 1}
+	t.rows.setRate(float64(cfg.RowsPerSecond))
+	t.batches.setRate(float64(cfg.BatchesPerSecond))
+	return t, nil
+}
+
+// WaitRows aguarda até que n linhas possam ser lidas
+func (t *Throttler) WaitRows(ctx context.Context, n int) error {
+	if t == nil {
+		return nil
+	}
+	t.rowsRead.Add(int64(n))
+	return t.rows.wait(ctx, n)
+}
+
+// WaitBatch aguarda até que um lote possa ser gravado
+func (t *Throttler) WaitBatch(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.batches.wait(ctx, 1)
+}
+
+// Run consulta o MySQL periodicamente e ajusta o nível até o contexto ser cancelado.
+// Não faz nada fora do modo adaptativo
+func (t *Throttler) Run(ctx context.Context) {
+	if t == nil || !t.cfg.Adaptive.Enabled {
+		return
+	}
+
+	interval := time.Duration(t.cfg.Adaptive.IntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			observed := float64(t.rowsRead.Swap(0)) / now.Sub(last).Seconds()
+			last = now
+
+			reason, err := t.check(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logging.Warnf("erro ao consultar a carga do MySQL para o throttling: %v", err)
+				continue
+			}
+			t.adjust(reason, observed)
+		}
+	}
+}
+
+// check consulta os indicadores configurados e retorna o motivo da sobrecarga, ou vazio se estiver tudo dentro dos limites
+func (t *Throttler) check(ctx context.Context) (string, error) {
+	adaptive := t.cfg.Adaptive
+
+	if adaptive.MaxThreadsRunning > 0 {
+		var name, value string
+		if err := t.db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Threads_running'").Scan(&name, &value); err != nil {
+			return "", err
+		}
+		running, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("valor inválido de Threads_running '%s'", value)
+		}
+		if running > adaptive.MaxThreadsRunning {
+			return fmt.Sprintf("Threads_running %d > %d", running, adaptive.MaxThreadsRunning), nil
+		}
+	}
+
+	if adaptive.MaxReplicaLagSeconds > 0 {
+		lag, ok, err := replicaLag(ctx, t.replica)
+		if err != nil {
+			return "", err
+		}
+		if ok && lag > adaptive.MaxReplicaLagSeconds {
+			return fmt.Sprintf("atraso da réplica %ds > %ds", lag, adaptive.MaxReplicaLagSeconds), nil
+		}
+	}
+
+	if adaptive.MaxQueryLatencyMs > 0 {
+		start := time.Now()
+		var one int
+		if err := t.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+			return "", err
+		}
+		latency := time.Since(start)
+		if limit := time.Duration(adaptive.MaxQueryLatencyMs) * time.Millisecond; latency > limit {
+			return fmt.Sprintf("latência %v > %v", latency.Round(time.Millisecond), limit), nil
+		}
+	}
+
+	return "", nil
+}
+
+// adjust reduz o nível quando há sobrecarga e o recupera aos poucos quando os indicadores normalizam
+func (t *Throttler) adjust(reason string, observed float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := t.level
+	if reason != "" {
+		// Sem limite configurado, a referência é a velocidade medida antes da primeira redução
+		if t.cfg.RowsPerSecond == 0 && t.level == 1 {
+			t.baseline = observed
+		}
+		t.level *= decreaseFactor
+		if t.level < t.cfg.Adaptive.MinLevel {
+			t.level = t.cfg.Adaptive.MinLevel
+		}
+		t.reason = reason
+	} else {
+		t.level *= increaseFactor
+		if t.level >= 1 {
+			t.level = 1
+			t.reason = ""
+		}
+	}
+	if t.level == previous {
+		return
+	}
+
+	base := float64(t.cfg.RowsPerSecond)
+	if base == 0 {
+		base = t.baseline
+	}
+	switch {
+	case t.level == 1:
+		t.rows.setRate(float64(t.cfg.RowsPerSecond))
+		logging.Infof("Throttling: velocidade normal restabelecida")
+	case base > 0:
+		t.rows.setRate(base * t.level)
+		if reason != "" {
+			logging.Infof("Throttling: velocidade reduzida para %.0f%% (%s)", t.level*100, reason)
+		}
+	}
+}
+
+// Status descreve o nível atual para o log de progresso
+func (t *Throttler) Status() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var parts []string
+	if t.cfg.Adaptive.Enabled {
+		parts = append(parts, fmt.Sprintf("nível %.0f%%", t.level*100))
+	}
+	t.rows.mu.Lock()
+	rate := t.rows.rate
+	t.rows.mu.Unlock()
+	if rate > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f linhas/seg", rate))
+	}
+	if t.cfg.BatchesPerSecond > 0 {
+		parts = append(parts, fmt.Sprintf("%d lotes/seg", t.cfg.BatchesPerSecond))
+	}
+	if t.reason != "" {
+		parts = append(parts, t.reason)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Close fecha a conexão com a réplica, se for diferente do servidor de origem
+func (t *Throttler) Close() error {
+	if t == nil || t.replica == t.db {
+		return nil
+	}
+	return t.replica.Close()
+}
+
+// replicaLag lê o atraso de replicação do SHOW REPLICA STATUS (ou SHOW SLAVE STATUS nas versões antigas).
+// Retorna false se o servidor não for uma réplica
+func replicaLag(ctx context.Context, db *sql.DB) (int, bool, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, false, err
+	}
+	if !rows.Next() {
+		return 0, false, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return 0, false, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		// NULL indica que a replicação está parada; trata como atraso desconhecido
+		if !values[i].Valid {
+			return 0, false, nil
+		}
+		lag, err := strconv.Atoi(values[i].String)
+		if err != nil {
+			return 0, false, fmt.Errorf("valor inválido de %s '%s'", column, values[i].String)
+		}
+		return lag, true, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,137 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"MysqlToMongo/internal/config"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ThrottleConfig
+		wantErr bool
+	}{
+		{"sem limites", config.ThrottleConfig{}, false},
+		{"linhas por segundo", config.ThrottleConfig{RowsPerSecond: 1000}, false},
+		{"limite negativo", config.ThrottleConfig{BatchesPerSecond: -1}, true},
+		{"adaptativo sem indicadores", config.ThrottleConfig{Adaptive: config.AdaptiveThrottleConfig{Enabled: true}}, true},
+		{"adaptativo com latência", config.ThrottleConfig{Adaptive: config.AdaptiveThrottleConfig{Enabled: true, MaxQueryLatencyMs: 50}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewWithoutLimitsReturnsNil(t *testing.T) {
+	throttler, err := New(config.ThrottleConfig{}, nil, nil)
+	if err != nil || throttler != nil {
+		t.Fatalf("New() = %v, %v; esperado nil sem limites", throttler, err)
+	}
+	// O throttler nulo não limita nada
+	if err := throttler.WaitRows(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := throttler.WaitBatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiterSpacesEvents(t *testing.T) {
+	var l limiter
+	l.setRate(1000)
+	ctx := context.Background()
+
+	start := time.Now()
+	// O primeiro bloco passa na hora e reserva 100ms para o seguinte
+	if err := l.wait(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.wait(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("200 eventos a 1000/s levaram %v, esperado ao menos 100ms", elapsed)
+	}
+}
+
+func TestLimiterStopsOnCancel(t *testing.T) {
+	var l limiter
+	l.setRate(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := l.wait(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := l.wait(ctx, 1); err == nil {
+		t.Error("wait() deveria retornar o erro do contexto cancelado")
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	cfg := config.ThrottleConfig{RowsPerSecond: 1000, Adaptive: config.AdaptiveThrottleConfig{Enabled: true, MaxQueryLatencyMs: 50, MinLevel: 0.2}}
+	throttler, err := New(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		reason    string
+		wantLevel float64
+		wantRate  float64
+	}{
+		{"latência alta", 0.5, 500},
+		{"latência alta", 0.25, 250},
+		// Não passa do nível mínimo configurado
+		{"latência alta", 0.2, 200},
+		{"", 0.25, 250},
+		{"", 0.3125, 312.5},
+		{"", 0.390625, 390.625},
+	}
+	for i, step := range steps {
+		throttler.adjust(step.reason, 0)
+		if throttler.level != step.wantLevel || throttler.rows.rate != step.wantRate {
+			t.Fatalf("passo %d: nível %v e taxa %v, esperado %v e %v", i, throttler.level, throttler.rows.rate, step.wantLevel, step.wantRate)
+		}
+	}
+
+	// Recupera até a velocidade configurada e limpa o motivo da redução
+	for i := 0; i < 10; i++ {
+		throttler.adjust("", 0)
+	}
+	if throttler.level != 1 || throttler.rows.rate != 1000 || throttler.reason != "" {
+		t.Errorf("nível %v, taxa %v e motivo %q após recuperar", throttler.level, throttler.rows.rate, throttler.reason)
+	}
+}
+
+func TestAdjustWithoutRowLimitUsesObservedSpeed(t *testing.T) {
+	cfg := config.ThrottleConfig{Adaptive: config.AdaptiveThrottleConfig{Enabled: true, MaxThreadsRunning: 20}}
+	throttler, err := New(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	throttler.adjust("Threads_running 40 > 20", 8000)
+	if throttler.rows.rate != 4000 {
+		t.Fatalf("taxa %v após a primeira redução, esperado metade da velocidade medida", throttler.rows.rate)
+	}
+	// A referência é a velocidade antes da primeira redução, não a já reduzida
+	throttler.adjust("Threads_running 40 > 20", 4000)
+	if throttler.rows.rate != 2000 {
+		t.Fatalf("taxa %v após a segunda redução, esperado 2000", throttler.rows.rate)
+	}
+
+	for throttler.level < 1 {
+		throttler.adjust("", 0)
+	}
+	if throttler.rows.rate != 0 {
+		t.Errorf("taxa %v após recuperar, esperado sem limite", throttler.rows.rate)
+	}
+}
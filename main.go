@@ -1,46 +1,248 @@
 package main
 
 import (
-	"context"
-	"log"
-	"time"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"MysqlToMongo/internal/config"
-	"MysqlToMongo/internal/database"
-	"MysqlToMongo/internal/migration"
+	"MysqlToMongo/internal/logging"
 )
 
-func main() {
-	// Inicia o timer
-	startTime := time.Now()
+// Nome do programa mostrado na ajuda
+const programName = "MysqlToMongo"
 
-	// Carrega configuração
-	config, err := config.LoadConfig()
-	if err != nil {
-		log.Fatalf("Erro ao carregar configuração: %v", err)
+// Código de saída usado quando a migração é interrompida por SIGINT/SIGTERM
+const exitInterrupted = 130
+
+// command representa um subcomando da linha de comando
+type command struct {
+	name    string
+	summary string
+	run     func(g *globalOptions, args []string)
+}
+
+// commands retorna os subcomandos na ordem em que aparecem na ajuda
+func commands() []command {
+	return []command{
+		{"migrate", "Migra a tabela do MySQL para o MongoDB, apagando antes a collection de destino (exceto com resume)", runMigrate},
+		{"validate-config", "Valida config.json e mapping.json; com --connect verifica também a tabela de origem", runValidateConfig},
+		{"preview", "Mostra como registros específicos são convertidos, sem gravar no MongoDB", runPreview},
+		{"verify", "Compara a origem com o que foi gravado no MongoDB", runVerify},
+		{"indexes", "Reconcilia os índices do MongoDB com os declarados na configuração", runIndexes},
+		{"search", "Busca documentos migrados por CPF, telefone, email, nome, CEP ou cidade", runSearch},
+		{"count", "Conta os registros do MySQL e os documentos de cada collection", runCount},
+		{"serve", "Expõe a API HTTP de consulta sobre a collection migrada", runServe},
+		{"generate-mapping", "Gera uma proposta de mapping.json a partir das colunas da tabela de origem", runGenerateMapping},
+		{"schema", "Exporta (schema export) ou aplica (schema apply) o validador $jsonSchema gerado do mapeamento", runSchema},
+		{"config", "Mostra a configuração efetiva, com includes, perfil e valores sobrescritos (config show)", runConfig},
 	}
+}
 
-	// Conecta ao MySQL
-	mysqlDB, err := database.ConnectMySQL(config)
-	if err != nil {
-		log.Fatalf("Erro ao conectar ao MySQL: %v", err)
+// globalOptions representa as flags aceitas por todos os comandos
+type globalOptions struct {
+	configPath  string
+	mappingPath string
+	profile     string
+	job         string
+	logLevel    string
+	logFormat   string
+	set         stringList
+}
+
+// register adiciona as flags globais ao conjunto de flags de um comando. Os valores já
+// informados antes do comando são mantidos como padrão
+func (g *globalOptions) register(fs *flag.FlagSet) {
+	if g.configPath == "" {
+		g.configPath = config.DefaultConfigPath
+	}
+	if g.mappingPath == "" {
+		g.mappingPath = config.DefaultMappingPath
+	}
+	fs.StringVar(&g.configPath, "config", g.configPath, "arquivo de configuração, em JSON ou YAML")
+	fs.StringVar(&g.mappingPath, "mapping", g.mappingPath, "arquivo de mapeamento das colunas")
+	fs.StringVar(&g.profile, "profile", g.profile, "perfil da seção profiles da configuração, ex.: homolog (padrão $MTM_PROFILE)")
+	fs.StringVar(&g.job, "job", g.job, "nome da migração; separa checkpoint e logs (general.job)")
+	fs.StringVar(&g.logLevel, "log-level", g.logLevel, "nível de log: debug, info, warn ou error (general.log_level)")
+	fs.StringVar(&g.logFormat, "log-format", g.logFormat, "formato do log: text ou json (general.log_format)")
+	fs.Var(&g.set, "set", "sobrescreve uma chave da configuração, ex.: --set mysql.host=db2 (pode repetir)")
+}
+
+// overrides retorna os valores sobrescritos na linha de comando. --job, --log-level e --log-format têm prioridade sobre --set
+func (g *globalOptions) overrides() []string {
+	overrides := append([]string(nil), g.set...)
+	if g.job != "" {
+		overrides = append(overrides, "general.job="+g.job)
+	}
+	if g.logLevel != "" {
+		overrides = append(overrides, "general.log_level="+g.logLevel)
+	}
+	if g.logFormat != "" {
+		overrides = append(overrides, "general.log_format="+g.logFormat)
 	}
-	defer mysqlDB.Close()
+	return overrides
+}
+
+// loadConfig carrega a configuração com os valores sobrescritos e aplica o nível e o formato do log
+func (g *globalOptions) loadConfig() *config.Config {
+	return g.load(false)
+}
 
-	// Conecta ao MongoDB
-	mongoClient, err := database.ConnectMongoDB(config)
+// loadConfigWithoutMapping carrega a configuração sem ler o mapeamento, que pode ainda não existir
+func (g *globalOptions) loadConfigWithoutMapping() *config.Config {
+	return g.load(true)
+}
+
+func (g *globalOptions) load(skipMapping bool) *config.Config {
+	cfg, err := config.Load(config.LoadOptions{
+		ConfigPath:  g.configPath,
+		MappingPath: g.mappingPath,
+		Profile:     g.profile,
+		Overrides:   g.overrides(),
+		SkipMapping: skipMapping,
+	})
+	if err != nil {
+		logging.Fatalf("Erro ao carregar configuração: %v", err)
+	}
+	level, err := logging.ParseLevel(cfg.General.LogLevel)
 	if err != nil {
-		log.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+		logging.Fatalf("Erro na configuração: %v", err)
+	}
+	logging.SetLevel(level)
+	if err := logging.SetFormat(cfg.General.LogFormat); err != nil {
+		logging.Fatalf("Erro na configuração: %v", err)
+	}
+	logging.Redact(cfg.Secrets()...)
+	return cfg
+}
+
+// stringList acumula os valores de uma flag repetida
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Nomes das flags globais, separadas das flags do comando na ajuda
+var globalFlagNames = map[string]bool{"config": true, "mapping": true, "profile": true, "job": true, "log-level": true, "log-format": true, "set": true}
+
+// newFlagSet cria o conjunto de flags de um comando, incluindo as flags globais
+func newFlagSet(name string, g *globalOptions) *flag.FlagSet {
+	var summary string
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			summary = cmd.summary
+		}
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	g.register(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Uso: %s %s [flags]\n\n%s\n", programName, name, summary)
+		if flags := formatFlags(fs, func(f *flag.Flag) bool { return !globalFlagNames[f.Name] }); flags != "" {
+			fmt.Fprintf(out, "\nFlags:\n%s", flags)
+		}
+		fmt.Fprintf(out, "\nFlags globais:\n%s", formatFlags(fs, func(f *flag.Flag) bool { return globalFlagNames[f.Name] }))
+	}
+	return fs
+}
+
+// formatFlags formata as flags selecionadas no estilo de flag.PrintDefaults
+func formatFlags(fs *flag.FlagSet, include func(*flag.Flag) bool) string {
+	var b strings.Builder
+	fs.VisitAll(func(f *flag.Flag) {
+		if !include(f) {
+			return
+		}
+		name, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(&b, "  --%s", f.Name)
+		if name != "" {
+			fmt.Fprintf(&b, " %s", name)
+		}
+		fmt.Fprintf(&b, "\n    \t%s", usage)
+		if f.DefValue != "" && f.DefValue != "false" {
+			fmt.Fprintf(&b, " (padrão %s)", f.DefValue)
+		}
+		b.WriteString("\n")
+	})
+	return b.String()
+}
+
+// parseFlags interpreta as flags do comando, que não aceita argumentos posicionais
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "Argumento inesperado: %q\n\n", fs.Arg(0))
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// usage mostra a ajuda geral com a lista de comandos
+func usage(out io.Writer) {
+	fmt.Fprintf(out, "Uso: %s [flags globais] <comando> [flags]\n\nComandos:\n", programName)
+	for _, cmd := range commands() {
+		fmt.Fprintf(out, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fs := flag.NewFlagSet(programName, flag.ContinueOnError)
+	(&globalOptions{}).register(fs)
+	fmt.Fprintf(out, "\nFlags globais (também aceitas depois do comando):\n%s", formatFlags(fs, func(*flag.Flag) bool { return true }))
+	fmt.Fprintf(out, "\nUse \"%s <comando> --help\" para as flags de cada comando.\n", programName)
+}
+
+func main() {
+	args := os.Args[1:]
+
+	// Flags globais antes do comando
+	global := &globalOptions{}
+	top := flag.NewFlagSet(programName, flag.ContinueOnError)
+	top.SetOutput(io.Discard)
+	global.register(top)
+	err := top.Parse(args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		usage(os.Stdout)
+		return
+	case err != nil:
+		// Flags de comando antes do nome do comando (ex.: --dry-run) não iniciam a migração
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		usage(os.Stderr)
+		os.Exit(2)
 	}
-	defer mongoClient.Disconnect(context.Background())
 
-	// Inicia migração
-	log.Println("Iniciando migração...")
-	if err := migration.MigrateData(config, mysqlDB, mongoClient); err != nil {
-		log.Fatalf("Erro durante a migração: %v", err)
+	// A migração apaga a collection de destino, então só roda com o comando migrate explícito
+	rest := top.Args()
+	if len(rest) == 0 {
+		fmt.Fprintf(os.Stderr, "Informe o comando; para migrar use \"%s migrate\"\n\n", programName)
+		usage(os.Stderr)
+		os.Exit(2)
 	}
 
-	// Calcula e mostra o tempo total
-	duration := time.Since(startTime)
-	log.Printf("Migração concluída com sucesso em %v!", duration)
+	name := rest[0]
+	if name == "help" {
+		if len(rest) > 1 {
+			name = rest[1]
+			rest = []string{name, "--help"}
+		} else {
+			usage(os.Stdout)
+			return
+		}
+	}
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			cmd.run(global, rest[1:])
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Comando desconhecido: %q\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
 }
@@ -1,2 +1,7 @@
 #!/bin/bash
-mongosh -u $1 -p $2 --eval 'use contatos_bd; db.createUser({user: "contatos_us", pwd: "contatos_67", roles: [{role: "readWrite", db: "contatos_bd"}]})' 
\ No newline at end of file
+# Uso: CONTATOS_PASSWORD=... ./createContatosDbMongo.sh <usuario_admin> <senha_admin>
+if [ -z "$CONTATOS_PASSWORD" ]; then
+    echo "Defina CONTATOS_PASSWORD com a senha do usuário contatos_us" >&2
+    exit 1
+fi
+mongosh -u "$1" -p "$2" --eval "use contatos_bd; db.createUser({user: \"contatos_us\", pwd: \"$CONTATOS_PASSWORD\", roles: [{role: \"readWrite\", db: \"contatos_bd\"}]})"